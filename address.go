@@ -0,0 +1,144 @@
+package corpbankclient
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/birapi/go-corpbankclient/iban"
+	"github.com/birapi/go-corpbankclient/identity"
+	"github.com/pkg/errors"
+)
+
+// AddrType is the type of a payment address. The types except IBAN are the FAST easy address
+// (Kolay Adres) aliases, which are resolved to the IBANs by the FAST system.
+type AddrType string
+
+const (
+	AddrTypeIBAN       AddrType = "IBAN"
+	AddrTypePhone      AddrType = "PHONE"
+	AddrTypeEmail      AddrType = "EMAIL"
+	AddrTypeNationalID AddrType = "NATIONAL_ID"
+)
+
+// Address is the destination address of a payment, either an IBAN or an easy address alias.
+// It should be created by one of IBANAddress, PhoneAddress, EmailAddress or NationalIDAddress.
+type Address struct {
+	Type  AddrType
+	Value string
+}
+
+// IBANAddress returns the address of a bank account.
+func IBANAddress(accountIBAN string) Address {
+	return Address{Type: AddrTypeIBAN, Value: accountIBAN}
+}
+
+// PhoneAddress returns the easy address alias of a Turkish mobile phone number, e.g. "+90 532 123 45 67".
+func PhoneAddress(phone string) Address {
+	return Address{Type: AddrTypePhone, Value: phone}
+}
+
+// EmailAddress returns the easy address alias of an email address.
+func EmailAddress(email string) Address {
+	return Address{Type: AddrTypeEmail, Value: email}
+}
+
+// NationalIDAddress returns the easy address alias of a national ID number (TCKN).
+func NationalIDAddress(tckn string) Address {
+	return Address{Type: AddrTypeNationalID, Value: tckn}
+}
+
+// IsZero reports whether the address is not set.
+func (a Address) IsZero() bool {
+	return a.Type == "" && a.Value == ""
+}
+
+func (a Address) String() string {
+	return string(a.Type) + ":" + a.Value
+}
+
+// Normalize validates the address according to its type, and returns it in the format
+// expected by the remote service.
+func (a Address) Normalize() (Address, error) {
+	var (
+		value string
+		err   error
+	)
+
+	switch a.Type {
+	case AddrTypeIBAN:
+		value = iban.Normalize(a.Value)
+		err = iban.Validate(value)
+
+	case AddrTypePhone:
+		value, err = normalizePhone(a.Value)
+
+	case AddrTypeEmail:
+		value, err = normalizeEmail(a.Value)
+
+	case AddrTypeNationalID:
+		value = strings.TrimSpace(a.Value)
+		err = identity.ValidateTCKN(value)
+
+	default:
+		return Address{}, errors.Wrapf(ErrInvalidPaymentOrder, "unknown address type: `%s`", a.Type)
+	}
+
+	if err != nil {
+		return Address{}, errors.Wrapf(ErrInvalidPaymentOrder, "invalid %s address: %s", a.Type, err.Error())
+	}
+
+	return Address{Type: a.Type, Value: value}, nil
+}
+
+// normalizePhone converts a Turkish mobile phone number to the E.164 format, e.g. "+905321234567".
+// The local formats with or without the leading zero and the country code are accepted.
+func normalizePhone(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+
+		return r
+	}, phone)
+
+	switch {
+	case strings.HasPrefix(digits, "+90"):
+		digits = digits[3:]
+	case strings.HasPrefix(digits, "0090"):
+		digits = digits[4:]
+	case strings.HasPrefix(digits, "90") && len(digits) == 12:
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		digits = digits[1:]
+	}
+
+	if len(digits) != 10 || digits[0] != '5' {
+		return "", errors.Errorf("not a Turkish mobile phone number: `%s`", phone)
+	}
+
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", errors.Errorf("not a Turkish mobile phone number: `%s`", phone)
+		}
+	}
+
+	return "+90" + digits, nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", errors.Errorf("not a plain email address: `%s`", email)
+	}
+
+	i := strings.LastIndex(email, "@")
+	if !strings.Contains(email[i+1:], ".") {
+		return "", errors.Errorf("the domain of the email address is not qualified: `%s`", email)
+	}
+
+	// the domain is case-insensitive, unlike the local part
+	return email[:i+1] + strings.ToLower(email[i+1:]), nil
+}
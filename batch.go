@@ -0,0 +1,272 @@
+package corpbankclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const defaultBatchParallelism = 4
+
+// batchNamespace is the default namespace of the idempotency keys derived from the reference codes.
+// It must never change, otherwise the payments of a batch run again after an upgrade would be sent
+// with new idempotency keys, and executed twice.
+const batchNamespace = "5c1b4a8e-3f0d-5e7a-9c62-d1f0b2a4e7c3"
+
+// BatchOptions customizes the behavior of MakePayments.
+type BatchOptions struct {
+	// Parallelism is the maximum number of the concurrent payment requests. Defaults to 4.
+	Parallelism int
+
+	// RateLimit is the maximum number of the payment requests per second. Unlimited if it is zero.
+	RateLimit float64
+
+	// BatchID identifies the batch, e.g. "payroll-2024-01". The idempotency keys of the payment orders
+	// without an IdempotencyKey are derived from it and their reference codes, so it must be the same
+	// in every run of a batch, and unique across the batches, as the reference codes are often reused,
+	// e.g. in the monthly payrolls. Either BatchID or IdempotencyNamespace is required to derive the keys.
+	BatchID string
+
+	// IdempotencyNamespace is the namespace of the derived idempotency keys. It can be used instead
+	// of BatchID, as a unique namespace per batch. Defaults to a fixed namespace.
+	IdempotencyNamespace uuid.UUID
+
+	// Checkpoint persists the results of the succeeded payments. When a batch is run again,
+	// e.g. after a crash, the payments found in the checkpoint are not sent again.
+	Checkpoint CheckpointStore
+}
+
+// BatchItemResult is the result of a payment order in a batch.
+type BatchItemResult struct {
+	// Index is the index of the payment order in the batch.
+	Index int
+
+	// IdempotencyKey is the idempotency key the payment order is sent with.
+	IdempotencyKey string
+
+	// Result is the result of the payment, nil if it fails.
+	Result *PaymentResult
+
+	// Err is the error of the payment, e.g. *Error for the errors of the remote service.
+	Err error
+
+	// Resumed reports whether the result is loaded from the checkpoint.
+	Resumed bool
+}
+
+// BatchResult is the result of MakePayments, in the order of the payment orders.
+type BatchResult struct {
+	Items []BatchItemResult
+}
+
+// Succeeded returns the number of the succeeded payments.
+func (r *BatchResult) Succeeded() int {
+	n := 0
+
+	for _, item := range r.Items {
+		if item.Err == nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Failed returns the results of the failed payments.
+func (r *BatchResult) Failed() []BatchItemResult {
+	var failed []BatchItemResult
+
+	for _, item := range r.Items {
+		if item.Err != nil {
+			failed = append(failed, item)
+		}
+	}
+
+	return failed
+}
+
+// MakePayments sends the payment orders concurrently. Each payment order is sent with an idempotency
+// key derived from the batch ID and its reference code, so the reference codes must be unique within
+// the batch. It returns ErrInvalidPaymentOrder if a key must be derived without a batch ID or namespace.
+// A failed payment does not stop the batch, its error is reported in its item. The returned error
+// is only about the checkpoint, the batch result is returned even if the checkpoint can not be saved.
+func (c *Client) MakePayments(ctx context.Context, paymentOrders []PaymentOrder, opts BatchOptions) (*BatchResult, error) {
+	namespace := opts.IdempotencyNamespace
+	if namespace == uuid.Nil {
+		if opts.BatchID == "" && needsDerivedKeys(paymentOrders) {
+			return nil, errors.Wrap(ErrInvalidPaymentOrder, "either the batch ID or the idempotency namespace is required to derive the idempotency keys")
+		}
+
+		namespace = uuid.MustParse(batchNamespace)
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultBatchParallelism
+	}
+
+	var done map[string]*PaymentResult
+
+	if opts.Checkpoint != nil {
+		var err error
+
+		done, err = opts.Checkpoint.Load(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load the checkpoint")
+		}
+	}
+
+	result := &BatchResult{Items: make([]BatchItemResult, len(paymentOrders))}
+	seen := map[string]int{}
+
+	var pending []int
+
+	for i := range paymentOrders {
+		item := &result.Items[i]
+		item.Index = i
+
+		o := &paymentOrders[i]
+
+		switch {
+		case o.IdempotencyKey != "":
+			item.IdempotencyKey = o.IdempotencyKey
+		case o.RefCode != "":
+			item.IdempotencyKey = uuid.NewSHA1(namespace, []byte(opts.BatchID+"\n"+o.RefCode)).String()
+		default:
+			item.Err = errors.Wrap(ErrInvalidPaymentOrder, "missing reference code to derive the idempotency key")
+			continue
+		}
+
+		if j, ok := seen[item.IdempotencyKey]; ok {
+			item.Err = errors.Wrapf(ErrInvalidPaymentOrder, "duplicate idempotency key of the payment order #%d: %s", j, item.IdempotencyKey)
+			continue
+		}
+
+		seen[item.IdempotencyKey] = i
+
+		if r, ok := done[item.IdempotencyKey]; ok {
+			item.Result = r
+			item.Resumed = true
+			continue
+		}
+
+		pending = append(pending, i)
+	}
+
+	var limiter *rateLimiter
+
+	if opts.RateLimit > 0 {
+		limiter = newRateLimiter(opts.RateLimit)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		saveErr error
+	)
+
+	queue := make(chan int)
+
+	for w := 0; w < parallelism && w < len(pending); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				item := &result.Items[i]
+
+				if err := limiter.wait(ctx); err != nil {
+					item.Err = errors.WithStack(err)
+					continue
+				}
+
+				o := paymentOrders[i]
+				o.IdempotencyKey = item.IdempotencyKey
+
+				item.Result, item.Err = c.MakePayment(ctx, o)
+
+				if item.Err != nil || opts.Checkpoint == nil {
+					continue
+				}
+
+				if err := opts.Checkpoint.Save(ctx, item.IdempotencyKey, item.Result); err != nil {
+					mu.Lock()
+					if saveErr == nil {
+						saveErr = errors.Wrapf(err, "unable to save the checkpoint of the payment order #%d", i)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	queued := 0
+
+enqueue:
+	for _, i := range pending {
+		select {
+		case queue <- i:
+			queued++
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	// the payment orders which are not queued are not sent, when the context is done
+	for _, i := range pending[queued:] {
+		result.Items[i].Err = errors.WithStack(ctx.Err())
+	}
+
+	return result, saveErr
+}
+
+// needsDerivedKeys reports whether any of the payment orders has no idempotency key.
+func needsDerivedKeys(paymentOrders []PaymentOrder) bool {
+	for i := range paymentOrders {
+		if paymentOrders[i].IdempotencyKey == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rateLimiter is a token bucket of a single token, which is refilled an interval after it is taken.
+// Unlike a ticker, the first request is not delayed.
+type rateLimiter struct {
+	tokens   chan struct{}
+	interval time.Duration
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	l := &rateLimiter{
+		tokens:   make(chan struct{}, 1),
+		interval: time.Duration(float64(time.Second) / rate),
+	}
+
+	l.tokens <- struct{}{}
+
+	return l
+}
+
+// wait takes the token, waiting for it to be refilled if necessary. A nil limiter does not wait.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	select {
+	case <-l.tokens:
+		time.AfterFunc(l.interval, func() { l.tokens <- struct{}{} })
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
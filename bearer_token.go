@@ -2,12 +2,15 @@ package corpbankclient
 
 import (
 	"bytes"
-	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,26 +25,185 @@ type tokenJSON struct {
 	Signature   string `json:"signature"`
 }
 
+// SigningAlgo is the signing scheme of a bearer token.
+type SigningAlgo string
+
+const (
+	// SigningAlgoHMACSHA256 signs the timestamp and the request body.
+	SigningAlgoHMACSHA256 SigningAlgo = "HMAC-SHA256"
+
+	// SigningAlgoHMACSHA256V2 signs the timestamp, the method, the path, the query
+	// and the digest of the request body.
+	SigningAlgoHMACSHA256V2 SigningAlgo = "HMAC-SHA256-V2"
+)
+
 type BearerToken struct {
 	APIKeyID  uuid.UUID
 	Timestamp time.Time
+
+	// Algo is the signing scheme of the token. Defaults to SigningAlgoHMACSHA256.
+	Algo      SigningAlgo
 	Signature []byte
 }
 
+// CanonicalRequest is the part of an HTTP request covered by the SigningAlgoHMACSHA256V2 signatures.
+type CanonicalRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+
+	// BodyDigest is the SHA-256 digest of the request body.
+	BodyDigest []byte
+}
+
 const (
 	maxPackedLen = 1024
 )
 
+// DigestBody returns the SHA-256 digest of the body streamed from the reader. It can be
+// precomputed and set as the BodyDigest of a CanonicalRequest, to sign without reading the body again.
+func DigestBody(body io.Reader) ([]byte, error) {
+	h := sha256.New()
+
+	if _, err := io.Copy(h, body); err != nil {
+		return nil, errors.Wrap(err, "unable to read the body to digest")
+	}
+
+	return h.Sum(nil), nil
+}
+
+// NewCanonicalRequest returns the canonical request of the given HTTP request and its body.
+func NewCanonicalRequest(req *http.Request, body []byte) *CanonicalRequest {
+	digest := sha256.Sum256(body)
+
+	return &CanonicalRequest{
+		Method:     req.Method,
+		Path:       req.URL.EscapedPath(),
+		Query:      req.URL.Query(),
+		BodyDigest: digest[:],
+	}
+}
+
+// Sign signs the content with the SigningAlgoHMACSHA256 scheme.
 func (t *BearerToken) Sign(apiKeySecret, contentToSign []byte) error {
-	t.Signature = t.sign(apiKeySecret, contentToSign)
+	return t.SignReader(apiKeySecret, bytes.NewReader(contentToSign))
+}
+
+// SignReader signs the content streamed from the reader with the SigningAlgoHMACSHA256 scheme,
+// without buffering it.
+func (t *BearerToken) SignReader(apiKeySecret []byte, contentToSign io.Reader) error {
+	if t.Algo != "" && t.Algo != SigningAlgoHMACSHA256 {
+		return errors.Errorf("the signing algorithm requires the request details: `%s`", t.Algo)
+	}
+
+	return t.SignWith(NewHMACSigner(apiKeySecret), nil, contentToSign)
+}
+
+// SignCanonical signs the canonical request with the SigningAlgoHMACSHA256V2 scheme.
+func (t *BearerToken) SignCanonical(apiKeySecret []byte, cr *CanonicalRequest) error {
+	return t.SignCanonicalWith(NewHMACSigner(apiKeySecret), cr)
+}
+
+// SignRequest signs the HTTP request with its body, by the signing scheme of the token.
+func (t *BearerToken) SignRequest(apiKeySecret []byte, req *http.Request, body []byte) error {
+	return t.SignWith(NewHMACSigner(apiKeySecret), req, bytes.NewReader(body))
+}
+
+// SignRequestReader signs the HTTP request with its body streamed from the reader, by the signing
+// scheme of the token. The body is hashed while it is read, without buffering it.
+func (t *BearerToken) SignRequestReader(apiKeySecret []byte, req *http.Request, body io.Reader) error {
+	return t.SignWith(NewHMACSigner(apiKeySecret), req, body)
+}
+
+// SignWith signs the HTTP request with its body streamed from the reader by the signer,
+// by the signing scheme of the token. The request can be nil for the SigningAlgoHMACSHA256 scheme.
+func (t *BearerToken) SignWith(signer Signer, req *http.Request, body io.Reader) error {
+	if t.Algo == SigningAlgoHMACSHA256V2 {
+		if req == nil {
+			return errors.Errorf("the signing algorithm requires the request details: `%s`", t.Algo)
+		}
+
+		digest, err := DigestBody(body)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return t.SignCanonicalWith(signer, &CanonicalRequest{
+			Method:     req.Method,
+			Path:       req.URL.EscapedPath(),
+			Query:      req.URL.Query(),
+			BodyDigest: digest,
+		})
+	}
+
+	sig, err := t.sign(signer, body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	t.Algo = SigningAlgoHMACSHA256
+	t.Signature = sig
 
 	return nil
 }
 
+// SignCanonicalWith signs the canonical request by the signer with the SigningAlgoHMACSHA256V2 scheme.
+func (t *BearerToken) SignCanonicalWith(signer Signer, cr *CanonicalRequest) error {
+	sig, err := t.sign(signer, bytes.NewReader(cr.canonical()))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	t.Algo = SigningAlgoHMACSHA256V2
+	t.Signature = sig
+
+	return nil
+}
+
+// Verify verifies the token signed over the content with the SigningAlgoHMACSHA256 scheme.
 func (t *BearerToken) Verify(apiKeySecret, contentToSign []byte, maxClockSkew time.Duration) error {
+	if t.Algo != "" && t.Algo != SigningAlgoHMACSHA256 {
+		return errors.Errorf("the signing algorithm requires the request details: `%s`", t.Algo)
+	}
+
+	return t.VerifyWith(NewHMACSigner(apiKeySecret), nil, contentToSign, maxClockSkew)
+}
+
+// VerifyCanonical verifies the token signed over the canonical request with the SigningAlgoHMACSHA256V2 scheme.
+func (t *BearerToken) VerifyCanonical(apiKeySecret []byte, cr *CanonicalRequest, maxClockSkew time.Duration) error {
+	if t.Algo != SigningAlgoHMACSHA256V2 {
+		return errors.Errorf("unexpected signing algorithm: `%s`", t.Algo)
+	}
+
+	return t.verify(NewHMACSigner(apiKeySecret), cr.canonical(), maxClockSkew)
+}
+
+// VerifyRequest verifies the token signed over the HTTP request and its body, by the signing scheme of the token.
+func (t *BearerToken) VerifyRequest(apiKeySecret []byte, req *http.Request, body []byte, maxClockSkew time.Duration) error {
+	return t.VerifyWith(NewHMACSigner(apiKeySecret), req, body, maxClockSkew)
+}
+
+// VerifyWith verifies the token signed over the HTTP request and its body by the signer, by the signing
+// scheme of the token. The request can be nil for the SigningAlgoHMACSHA256 scheme.
+func (t *BearerToken) VerifyWith(signer Signer, req *http.Request, body []byte, maxClockSkew time.Duration) error {
+	if t.Algo == SigningAlgoHMACSHA256V2 {
+		if req == nil {
+			return errors.Errorf("the signing algorithm requires the request details: `%s`", t.Algo)
+		}
+
+		return t.verify(signer, NewCanonicalRequest(req, body).canonical(), maxClockSkew)
+	}
+
+	return t.verify(signer, body, maxClockSkew)
+}
+
+func (t *BearerToken) verify(signer Signer, contentToSign []byte, maxClockSkew time.Duration) error {
 	now := time.Now()
 
-	calculatedSig := t.sign(apiKeySecret, contentToSign)
+	calculatedSig, err := t.sign(signer, bytes.NewReader(contentToSign))
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	if subtle.ConstantTimeCompare(t.Signature, calculatedSig) != 1 {
 		return errors.New("illegal signature")
@@ -57,20 +219,84 @@ func (t *BearerToken) Verify(apiKeySecret, contentToSign []byte, maxClockSkew ti
 	return nil
 }
 
-func (t *BearerToken) sign(secret, contentToSign []byte) []byte {
-	h := hmac.New(sha256.New, secret)
+// sign signs the timestamp followed by the content.
+func (t *BearerToken) sign(signer Signer, contentToSign io.Reader) ([]byte, error) {
+	msg := io.MultiReader(strings.NewReader(t.Timestamp.UTC().Format(time.RFC3339)), contentToSign)
+
+	sig, err := signer.Sign(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to sign the bearer token")
+	}
+
+	return sig, nil
+}
+
+// canonical returns the newline separated method, path, query and body digest, prefixed by a newline
+// to separate it from the timestamp.
+func (cr *CanonicalRequest) canonical() []byte {
+	var b strings.Builder
+
+	b.WriteString("\n")
+	b.WriteString(strings.ToUpper(cr.Method))
+	b.WriteString("\n")
+	b.WriteString(canonicalPath(cr.Path))
+	b.WriteString("\n")
+	b.WriteString(canonicalQuery(cr.Query))
+	b.WriteString("\n")
+	b.WriteString(hex.EncodeToString(cr.BodyDigest))
+
+	return []byte(b.String())
+}
+
+// canonicalPath re-escapes each segment of the path, so that the equivalent encodings
+// of the same path produce the same result.
+func canonicalPath(p string) string {
+	segs := strings.Split(strings.Trim(p, "/"), "/")
+
+	for i, seg := range segs {
+		if v, err := url.PathUnescape(seg); err == nil {
+			seg = v
+		}
+
+		segs[i] = url.PathEscape(seg)
+	}
+
+	return "/" + strings.Join(segs, "/")
+}
+
+// canonicalQuery encodes the query sorted by the keys and the values.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
 
-	h.Write([]byte(t.Timestamp.UTC().Format(time.RFC3339)))
-	h.Write(contentToSign)
+	var pairs []string
+
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+
+		for _, v := range vals {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
 
-	return h.Sum(nil)
+	return strings.Join(pairs, "&")
 }
 
 func (t *BearerToken) Pack() (string, error) {
+	algo := t.Algo
+	if algo == "" {
+		algo = SigningAlgoHMACSHA256
+	}
+
 	packed, err := json.Marshal(&tokenJSON{
 		APIKeyID:    t.APIKeyID.String(),
 		Timestamp:   t.Timestamp.Format(time.RFC3339),
-		SigningAlgo: "HMAC-SHA256",
+		SigningAlgo: string(algo),
 		Signature:   hex.EncodeToString(t.Signature),
 	})
 
@@ -106,7 +332,16 @@ func (t *BearerToken) Unpack(packed string) error {
 		return errors.Wrapf(err, "unable to parse the timestamp value: `%s`", token.Timestamp)
 	}
 
-	if strings.ToLower(strings.TrimSpace(token.SigningAlgo)) != "hmac-sha256" {
+	var algo SigningAlgo
+
+	switch strings.ToUpper(strings.TrimSpace(token.SigningAlgo)) {
+	case string(SigningAlgoHMACSHA256):
+		algo = SigningAlgoHMACSHA256
+
+	case string(SigningAlgoHMACSHA256V2):
+		algo = SigningAlgoHMACSHA256V2
+
+	default:
 		return errors.Errorf("unsupported signing algorithm: `%s`", token.SigningAlgo)
 	}
 
@@ -117,6 +352,7 @@ func (t *BearerToken) Unpack(packed string) error {
 
 	t.APIKeyID = apiKeyID
 	t.Timestamp = timestamp
+	t.Algo = algo
 	t.Signature = sig
 
 	return nil
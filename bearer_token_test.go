@@ -0,0 +1,135 @@
+package corpbankclient
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/v1/payments", "/v1/payments"},
+		{"/v1/payments/", "/v1/payments"},
+		{"v1/payments", "/v1/payments"},
+		{"/v1/payments//", "/v1/payments"},
+		{"/v1/a%20b", "/v1/a%20b"},
+		{"/v1/a b", "/v1/a%20b"},
+		{"/v1/%7Euser", "/v1/~user"},
+		{"/v1/a%2Fb", "/v1/a%2Fb"},
+		{"/v1/%zz", "/v1/%25zz"},
+	}
+
+	for _, tt := range tests {
+		if got := canonicalPath(tt.path); got != tt.want {
+			t.Errorf("canonicalPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"a=1", "a=1"},
+		{"b=2&a=1", "a=1&b=2"},
+		{"b=3&a=1&b=2", "a=1&b=2&b=3"},
+		{"q=x%20y&p=a%2Bb", "p=a%2Bb&q=x+y"},
+		{"empty=&a=1", "a=1&empty="},
+	}
+
+	for _, tt := range tests {
+		q, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := canonicalQuery(q); got != tt.want {
+			t.Errorf("canonicalQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSignCanonicalVectors(t *testing.T) {
+	ts := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		want   string
+	}{
+		{"GET", "/v1/payments/?b=3&a=1&b=2", "", "a72a635d767b52dddddeb4f1aada5c029c95df3a909462b1b71c5c6a931ea431"},
+		{"get", "/v1/payments?a=1&b=2&b=3", "", "a72a635d767b52dddddeb4f1aada5c029c95df3a909462b1b71c5c6a931ea431"},
+		{"POST", "/v1/payments", "{}", "286b647fcc1ffca5f1a7fb3fc999c5809690dec6c94f34d7b55893a3b8e625b4"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+
+		token := &BearerToken{Timestamp: ts}
+		if err := token.SignCanonical([]byte("secret"), NewCanonicalRequest(req, []byte(tt.body))); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := hex.EncodeToString(token.Signature); got != tt.want {
+			t.Errorf("signature of %s %s = %s, want %s", tt.method, tt.target, got, tt.want)
+		}
+
+		if token.Algo != SigningAlgoHMACSHA256V2 {
+			t.Errorf("algorithm of %s %s = %s, want %s", tt.method, tt.target, token.Algo, SigningAlgoHMACSHA256V2)
+		}
+	}
+}
+
+func TestVerifyRequestCanonical(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"amount":"10.00"}`)
+
+	signed := httptest.NewRequest("POST", "/v1/payments/?b=2&a=1", nil)
+
+	token := &BearerToken{Timestamp: time.Now(), Algo: SigningAlgoHMACSHA256V2}
+	if err := token.SignRequest(secret, signed, body); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   []byte
+		valid  bool
+	}{
+		{"same request", "POST", "/v1/payments/?b=2&a=1", body, true},
+		{"without the trailing slash", "POST", "/v1/payments?b=2&a=1", body, true},
+		{"reordered query", "POST", "/v1/payments?a=1&b=2", body, true},
+		{"different method", "PUT", "/v1/payments?a=1&b=2", body, false},
+		{"different path", "POST", "/v1/payment?a=1&b=2", body, false},
+		{"different query", "POST", "/v1/payments?a=1&b=3", body, false},
+		{"extra query parameter", "POST", "/v1/payments?a=1&b=2&c=3", body, false},
+		{"different body", "POST", "/v1/payments?a=1&b=2", []byte(`{"amount":"11.00"}`), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+
+			err := token.VerifyRequest(secret, req, tt.body, time.Minute)
+			if tt.valid && err != nil {
+				t.Fatalf("VerifyRequest() = %v, want nil", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Fatal("VerifyRequest() = nil, want an error")
+			}
+		})
+	}
+}
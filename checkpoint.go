@@ -0,0 +1,107 @@
+package corpbankclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CheckpointStore persists the results of the succeeded payments of a batch, by their idempotency keys.
+type CheckpointStore interface {
+	// Load returns the saved results by their idempotency keys.
+	Load(ctx context.Context) (map[string]*PaymentResult, error)
+
+	// Save saves the result of the payment. It is called concurrently.
+	Save(ctx context.Context, idempotencyKey string, result *PaymentResult) error
+}
+
+type checkpointEntry struct {
+	IdempotencyKey string         `json:"idempotencyKey"`
+	Result         *PaymentResult `json:"result"`
+}
+
+// FileCheckpointStore is a CheckpointStore backed by a JSON Lines file. The file is created if it
+// does not exist, and each result is appended and synced, so that it survives a crash.
+type FileCheckpointStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *FileCheckpointStore) Load(ctx context.Context) (map[string]*PaymentResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := map[string]*PaymentResult{}
+
+	f, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return results, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the checkpoint file: `%s`", s.Path)
+	}
+
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		// the malformed lines are skipped, since they can only be partially written while crashing,
+		// and sending the same payment again with the same idempotency key is safe
+		e := &checkpointEntry{}
+		if err := json.Unmarshal(sc.Bytes(), e); err != nil || e.IdempotencyKey == "" {
+			continue
+		}
+
+		results[e.IdempotencyKey] = e.Result
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the checkpoint file: `%s`", s.Path)
+	}
+
+	return results, nil
+}
+
+func (s *FileCheckpointStore) Save(ctx context.Context, idempotencyKey string, result *PaymentResult) error {
+	line, err := json.Marshal(&checkpointEntry{IdempotencyKey: idempotencyKey, Result: result})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open the checkpoint file: `%s`", s.Path)
+	}
+
+	// the partially written line of a previous crash is terminated, not to corrupt the new line
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, fi.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "unable to write the checkpoint file: `%s`", s.Path)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "unable to sync the checkpoint file: `%s`", s.Path)
+	}
+
+	return errors.WithStack(f.Close())
+}
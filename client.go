@@ -2,6 +2,7 @@ package corpbankclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -9,24 +10,86 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sync"
 	"time"
 
+	"github.com/birapi/go-corpbankclient/transferhours"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 )
 
 type Client struct {
+	mu          sync.RWMutex
 	keyID       uuid.UUID
-	keySec      []byte
+	signer      Signer
 	baseURL     *url.URL
 	hc          *http.Client
 	maxTimeDiff time.Duration
+	retryPolicy *RetryPolicy
+	callbackURL string
+	replayStore ReplayStore
+	replayTTL   time.Duration
+	verifier    *WebhookVerifier
+	signingAlgo SigningAlgo
+	fastLimit   decimal.Decimal
+	calendar    *transferhours.Calendar
+
+	provider          CredentialsProvider
+	resolvedCreds     Credentials
+	refreshInterval   time.Duration
+	nextRefresh       time.Time
+	onRefreshError    func(error)
+	retireGracePeriod time.Duration
 }
 
 type ClientOptions struct {
 	APIBaseURL  string
 	HTTPClient  *http.Client
 	MaxTimeDiff time.Duration
+
+	// RetryPolicy enables retrying the failed requests. The requests are not retried if it is nil.
+	RetryPolicy *RetryPolicy
+
+	// DefaultCallbackURL receives the status notifications of the payments
+	// which have no CallbackURL in their PaymentOrder.
+	DefaultCallbackURL string
+
+	// WebhookReplayStore enables deduplicating the webhook notifications by their signatures
+	// and event IDs (e.g. transaction ID). The duplicates are acknowledged without invoking the handler,
+	// and the concurrent deliveries of an event in progress are rejected with 409 to be redelivered.
+	WebhookReplayStore ReplayStore
+
+	// WebhookReplayTTL is the duration to remember the processed events. Defaults to 24 hours.
+	WebhookReplayTTL time.Duration
+
+	// WebhookVerifier verifies the webhook notifications against its keyring.
+	// Defaults to a verifier with only the API key of the client.
+	WebhookVerifier *WebhookVerifier
+
+	// SigningAlgo is the signing scheme of the requests. Defaults to SigningAlgoHMACSHA256.
+	SigningAlgo SigningAlgo
+
+	// FASTLimit is the maximum amount of a FAST transfer, it is checked for the explicit FAST payments
+	// and used by the automatic transfer method selection. Defaults to 100,000.
+	FASTLimit decimal.Decimal
+
+	// TransferCalendar enables rejecting the immediate EFT payments out of the EFT hours with
+	// ErrOutOfEFTHours, before they are sent. The EFT payments on the days of the years which the
+	// holidays of the calendar do not cover are rejected with transferhours.ErrUnknownYear.
+	TransferCalendar *transferhours.Calendar
+
+	// CredentialsRefreshInterval is the interval to re-resolve the credentials of the clients
+	// created by NewClientWithProvider. Defaults to 1 minute.
+	CredentialsRefreshInterval time.Duration
+
+	// OnCredentialsRefreshError is called when the credentials can not be re-resolved by the provider,
+	// while the client keeps using the last resolved credentials.
+	OnCredentialsRefreshError func(err error)
+
+	// CredentialsGracePeriod is the duration to keep accepting the webhook notifications signed by
+	// the previous API key, after the provider resolves a new one. Defaults to 15 minutes.
+	CredentialsGracePeriod time.Duration
 }
 
 const (
@@ -35,24 +98,59 @@ const (
 
 	defaultServiceURL  = "https://api.birapi.com/corpbank/aispis/v1"
 	defaultMaxTimeDiff = 10 * time.Minute
+
+	defaultCredentialsRefreshInterval = time.Minute
 )
 
+var defaultFASTLimit = decimal.NewFromInt(100000)
+
 func NewClient(apiCreds Credentials, clientOpts *ClientOptions) (*Client, error) {
-	apiKeyID, err := uuid.Parse(apiCreds.APIKeyID)
+	apiKeyID, apiKeySec, err := parseCredentials(apiCreds)
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to parse API key ID: `%s`", apiCreds.APIKeyID)
+		return nil, errors.WithStack(err)
 	}
 
-	apiKeySec, err := base64.StdEncoding.DecodeString(apiCreds.APIKeySecret)
+	return NewClientWithSigner(apiKeyID, NewHMACSigner(apiKeySec), clientOpts)
+}
+
+// NewClientWithProvider returns a client with the credentials resolved by the provider. The credentials
+// are re-resolved by the first request after each CredentialsRefreshInterval, or after the remote
+// service rejects the credentials, and the client switches to the new API key when they change.
+// If they can not be re-resolved, the client keeps using the last resolved credentials.
+func NewClientWithProvider(provider CredentialsProvider, clientOpts *ClientOptions) (*Client, error) {
+	apiCreds, err := provider.Credentials(context.Background())
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to parse API secret")
+		return nil, errors.WithStack(err)
 	}
 
+	c, err := NewClient(apiCreds, clientOpts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	c.provider = provider
+	c.resolvedCreds = apiCreds
+	c.nextRefresh = time.Now().Add(c.refreshInterval)
+
+	return c, nil
+}
+
+// NewClientWithSigner returns a client signing the requests by the given signer, e.g. backed by an HSM,
+// so that the API key secret is never loaded into the process memory.
+func NewClientWithSigner(apiKeyID uuid.UUID, signer Signer, clientOpts *ClientOptions) (*Client, error) {
+	var err error
+
 	c := &Client{
 		keyID:       apiKeyID,
-		keySec:      apiKeySec,
+		signer:      signer,
 		hc:          http.DefaultClient,
 		maxTimeDiff: defaultMaxTimeDiff,
+		replayTTL:   defaultReplayTTL,
+		signingAlgo: SigningAlgoHMACSHA256,
+		fastLimit:   defaultFASTLimit,
+
+		refreshInterval:   defaultCredentialsRefreshInterval,
+		retireGracePeriod: defaultRotationGracePeriod,
 	}
 
 	baseURL := defaultServiceURL
@@ -73,9 +171,217 @@ func NewClient(apiCreds Credentials, clientOpts *ClientOptions) (*Client, error)
 		c.maxTimeDiff = clientOpts.MaxTimeDiff
 	}
 
+	if clientOpts != nil && clientOpts.RetryPolicy != nil {
+		p := *clientOpts.RetryPolicy
+		c.retryPolicy = &p
+	}
+
+	if clientOpts != nil && clientOpts.DefaultCallbackURL != "" {
+		if err := validateCallbackURL(clientOpts.DefaultCallbackURL); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		c.callbackURL = clientOpts.DefaultCallbackURL
+	}
+
+	if clientOpts != nil && clientOpts.WebhookReplayStore != nil {
+		c.replayStore = clientOpts.WebhookReplayStore
+	}
+
+	if clientOpts != nil && clientOpts.WebhookReplayTTL > 0 {
+		c.replayTTL = clientOpts.WebhookReplayTTL
+	}
+
+	if clientOpts != nil && clientOpts.SigningAlgo != "" {
+		switch clientOpts.SigningAlgo {
+		case SigningAlgoHMACSHA256, SigningAlgoHMACSHA256V2:
+			c.signingAlgo = clientOpts.SigningAlgo
+
+		default:
+			return nil, errors.Errorf("unsupported signing algorithm: `%s`", clientOpts.SigningAlgo)
+		}
+	}
+
+	if clientOpts != nil && clientOpts.FASTLimit.IsPositive() {
+		c.fastLimit = clientOpts.FASTLimit
+	}
+
+	if clientOpts != nil && clientOpts.TransferCalendar != nil {
+		c.calendar = clientOpts.TransferCalendar
+	}
+
+	if clientOpts != nil && clientOpts.CredentialsRefreshInterval > 0 {
+		c.refreshInterval = clientOpts.CredentialsRefreshInterval
+	}
+
+	if clientOpts != nil && clientOpts.CredentialsGracePeriod > 0 {
+		c.retireGracePeriod = clientOpts.CredentialsGracePeriod
+	}
+
+	if clientOpts != nil {
+		c.onRefreshError = clientOpts.OnCredentialsRefreshError
+	}
+
+	if clientOpts != nil && clientOpts.WebhookVerifier != nil {
+		c.verifier = clientOpts.WebhookVerifier
+	} else {
+		c.verifier = &WebhookVerifier{keys: map[uuid.UUID]Signer{apiKeyID: signer}}
+	}
+
 	return c, nil
 }
 
+// KeyID returns the ID of the API key used to sign the requests.
+func (c *Client) KeyID() uuid.UUID {
+	keyID, _ := c.credentials()
+	return keyID
+}
+
+// SetCredentials switches the API key used to sign the requests. The new API key is also added
+// to the keyring of the webhook verifier, while the previous one is kept in the keyring.
+func (c *Client) SetCredentials(apiCreds Credentials) error {
+	apiKeyID, apiKeySec, err := parseCredentials(apiCreds)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.SetSigner(apiKeyID, NewHMACSigner(apiKeySec))
+
+	return nil
+}
+
+// RefreshCredentials re-resolves the credentials by the provider of the client, and switches
+// to the new API key if they have changed. The webhook notifications signed by the previous API key
+// are accepted for the CredentialsGracePeriod. It does nothing for the clients without a provider.
+func (c *Client) RefreshCredentials(ctx context.Context) error {
+	if c.provider == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.nextRefresh = time.Now().Add(c.refreshInterval)
+	c.mu.Unlock()
+
+	return c.refreshCredentials(ctx)
+}
+
+// refreshCredentialsIfDue re-resolves the credentials if the refresh interval has passed, and
+// reports the errors by the hook, since the last resolved credentials are still usable.
+func (c *Client) refreshCredentialsIfDue(ctx context.Context) {
+	if c.provider == nil {
+		return
+	}
+
+	// the refresh is claimed by moving the next refresh time, so the concurrent requests do not refresh
+	c.mu.Lock()
+	due := !time.Now().Before(c.nextRefresh)
+	if due {
+		c.nextRefresh = time.Now().Add(c.refreshInterval)
+	}
+	c.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	if err := c.refreshCredentials(ctx); err != nil && c.onRefreshError != nil {
+		c.onRefreshError(err)
+	}
+}
+
+// expireCredentials makes the next request re-resolve the credentials.
+func (c *Client) expireCredentials() {
+	if c.provider == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.nextRefresh = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *Client) refreshCredentials(ctx context.Context) error {
+	apiCreds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to resolve the credentials")
+	}
+
+	apiKeyID, apiKeySec, err := parseCredentials(apiCreds)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	signer := NewHMACSigner(apiKeySec)
+
+	// the credentials are compared and swapped atomically, so that a change is applied only once
+	c.mu.Lock()
+
+	if apiCreds == c.resolvedCreds {
+		c.mu.Unlock()
+		return nil
+	}
+
+	oldKeyID := c.keyID
+
+	c.resolvedCreds = apiCreds
+	c.keyID = apiKeyID
+	c.signer = signer
+
+	c.mu.Unlock()
+
+	c.verifier.AddSigner(apiKeyID, signer)
+
+	if oldKeyID != apiKeyID {
+		// the previous API key is removed from the keyring after the grace period, unless the client
+		// switches back to it in the meantime
+		time.AfterFunc(c.retireGracePeriod, func() {
+			if c.KeyID() != oldKeyID {
+				c.verifier.RemoveKey(oldKeyID)
+			}
+		})
+	}
+
+	return nil
+}
+
+// SetSigner switches the API key used to sign the requests, in the same way as SetCredentials.
+func (c *Client) SetSigner(apiKeyID uuid.UUID, signer Signer) {
+	c.verifier.AddSigner(apiKeyID, signer)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keyID = apiKeyID
+	c.signer = signer
+}
+
+func (c *Client) credentials() (uuid.UUID, Signer) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.keyID, c.signer
+}
+
+// WebhookVerifier returns the verifier of the webhook notifications. Its keyring can be
+// modified to accept the notifications signed by other API keys.
+func (c *Client) WebhookVerifier() *WebhookVerifier {
+	return c.verifier
+}
+
+func parseCredentials(apiCreds Credentials) (uuid.UUID, []byte, error) {
+	apiKeyID, err := uuid.Parse(apiCreds.APIKeyID)
+	if err != nil {
+		return uuid.Nil, nil, errors.Wrapf(err, "unable to parse API key ID: `%s`", apiCreds.APIKeyID)
+	}
+
+	apiKeySec, err := base64.StdEncoding.DecodeString(apiCreds.APIKeySecret)
+	if err != nil {
+		return uuid.Nil, nil, errors.Wrap(err, "unable to parse API secret")
+	}
+
+	return apiKeyID, apiKeySec, nil
+}
+
 func (c *Client) path(p ...string) string {
 	u := *c.baseURL
 
@@ -87,17 +393,38 @@ func (c *Client) path(p ...string) string {
 }
 
 func (c *Client) sign(req *http.Request) error {
+	// the last resolved credentials are used if they can not be refreshed
+	c.refreshCredentialsIfDue(req.Context())
+
+	keyID, signer := c.credentials()
+
 	token := &BearerToken{
-		APIKeyID:  c.keyID,
+		APIKeyID:  keyID,
 		Timestamp: time.Now(),
+		Algo:      c.signingAlgo,
 	}
 
-	var reqBuf []byte
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+		if err := token.SignWith(signer, req, http.NoBody); err != nil {
+			return errors.WithStack(err)
+		}
+
+	case req.GetBody != nil:
+		// the body is hashed from a copy while it streams, and the request body is left untouched
+		body, err := req.GetBody()
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-	if req.Body != nil && req.Body != http.NoBody {
-		var err error
+		defer body.Close()
 
-		reqBuf, err = io.ReadAll(req.Body)
+		if err := token.SignWith(signer, req, body); err != nil {
+			return errors.WithStack(err)
+		}
+
+	default:
+		reqBuf, err := io.ReadAll(req.Body)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -105,10 +432,10 @@ func (c *Client) sign(req *http.Request) error {
 		req.Body.Close()
 
 		req.Body = io.NopCloser(bytes.NewBuffer(reqBuf))
-	}
 
-	if err := token.Sign(c.keySec, reqBuf); err != nil {
-		return errors.WithStack(err)
+		if err := token.SignWith(signer, req, bytes.NewReader(reqBuf)); err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
 	packed, err := token.Pack()
@@ -122,13 +449,65 @@ func (c *Client) sign(req *http.Request) error {
 }
 
 func (c *Client) do(dst interface{}, req *http.Request, expectedStatusCode int) error {
+	if c.retryPolicy == nil || c.retryPolicy.MaxAttempts <= 1 {
+		_, _, err := c.doOnce(dst, req, expectedStatusCode)
+		return err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		reqBuf, err := io.ReadAll(req.Body)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		req.Body.Close()
+
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(reqBuf)), nil
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			attemptReq.Body = body
+		}
+
+		retryable, wait, err := c.doOnce(dst, attemptReq, expectedStatusCode)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable || attempt >= c.retryPolicy.MaxAttempts || !canRetry(req) || isPermanentErr(err) {
+			return err
+		}
+
+		if wait <= 0 {
+			wait = c.retryPolicy.backoff(attempt + 1)
+		}
+
+		if ctxErr := sleepCtx(req.Context(), wait); ctxErr != nil {
+			return errors.Wrapf(ctxErr, "retry canceled after %d attempts, last error: %s", attempt, err.Error())
+		}
+	}
+}
+
+// doOnce signs and sends the request. In case of an error, it also reports whether the request
+// is worth retrying, and the wait duration requested by the remote service, if any.
+func (c *Client) doOnce(dst interface{}, req *http.Request, expectedStatusCode int) (bool, time.Duration, error) {
 	if err := c.sign(req); err != nil {
-		return errors.WithStack(err)
+		return false, 0, errors.WithStack(err)
 	}
 
 	resp, err := c.hc.Do(req)
 	if err != nil {
-		return errors.WithStack(err)
+		return req.Context().Err() == nil, 0, errors.WithStack(err)
 	}
 
 	defer resp.Body.Close()
@@ -136,23 +515,29 @@ func (c *Client) do(dst interface{}, req *http.Request, expectedStatusCode int)
 	if resp.StatusCode != expectedStatusCode {
 		respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxReadBytesOnErr))
 		if err != nil {
-			return errors.Wrapf(err, "unable to read HTTP response for status code: %s (expected: %d)", resp.Status, expectedStatusCode)
+			// the status code alone decides, since the error code of the body is unknown
+			return retryableStatus(resp.StatusCode), c.retryPolicy.capInterval(retryAfter(resp.Header)),
+				errors.Wrapf(err, "unable to read HTTP response for status code: %s (expected: %d)", resp.Status, expectedStatusCode)
 		}
 
-		return errors.Wrapf(wrapErr(&errUnexpectedStatus{
-			StatusCode: resp.StatusCode,
-			RespBody:   respBody,
-		}), "remote service returns unexpected response: %s - %s", resp.Status, string(respBody))
+		apiErr := newError(resp, respBody)
+		wait := c.retryPolicy.capInterval(retryAfter(resp.Header))
 
+		// the credentials may have been rotated out of band
+		if errors.Is(apiErr, ErrUnauthorized) {
+			c.expireCredentials()
+		}
+
+		return apiErr.Retryable(), wait, errors.Wrapf(apiErr, "remote service returns unexpected response: %s (expected: %d)", resp.Status, expectedStatusCode)
 	}
 
 	if dst != nil {
 		dec := json.NewDecoder(io.LimitReader(resp.Body, maxReadBytes))
 
 		if err := dec.Decode(dst); err != nil {
-			return errors.Wrap(err, "unable to parse JSON response of the remote service")
+			return false, 0, errors.Wrap(err, "unable to parse JSON response of the remote service")
 		}
 	}
 
-	return nil
+	return false, 0, nil
 }
@@ -0,0 +1,941 @@
+// Package corpbanktest provides an in-process fake of the corporate banking AISPIS v1 API
+// to test the code that uses the corpbankclient package without hitting the real service.
+package corpbanktest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/birapi/go-corpbankclient/iban"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Error codes returned by the fake server. They are mapped to the sentinel errors of
+// the corpbankclient package (e.g. corpbankclient.ErrInsufficientBalance).
+const (
+	CodeCurrencyMismatch       = "CURRENCY_MISMATCH"
+	CodeIncorrectRecipientData = "INCORRECT_RECIPIENT_DATA"
+	CodeInsufficientBalance    = "INSUFFICIENT_BALANCE"
+	CodeInvalidRecipientID     = "INVALID_RECIPIENT_ID"
+	CodeOutOfEFTHours          = "OUT_OF_EFT_HOURS"
+
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeForbidden            = "FORBIDDEN"
+	CodeNotFound             = "NOT_FOUND"
+	CodeAccountNotFound      = "ACCOUNT_NOT_FOUND"
+	CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	CodeRateLimitExceeded    = "RATE_LIMIT_EXCEEDED"
+	CodeValidationError      = "VALIDATION_ERROR"
+)
+
+const (
+	defaultPageSize    = 20
+	defaultMaxTimeDiff = 10 * time.Minute
+	defaultCurrency    = "TRY"
+	maxReadBytes       = 10 * 1024 * 1024
+	paymentDateLayout  = "2006-01-02T15:04:05.000Z"
+)
+
+// Account is a bank account held by the fake server.
+type Account struct {
+	ID            uuid.UUID
+	IBAN          string
+	BankCode      string
+	Currency      string
+	Balance       decimal.Decimal
+	LastUpdatedAt time.Time
+}
+
+// ErrorInjection describes an error response to be returned by the fake server
+// instead of processing the matching request.
+type ErrorInjection struct {
+	// Method matches the HTTP method of the request. Empty value matches any method.
+	Method string
+
+	// Path matches the path of the request, e.g. "/payments". Empty value matches any path.
+	Path string
+
+	// StatusCode is the HTTP status code of the response. Defaults to 422.
+	StatusCode int
+
+	// Code and Message are returned in the JSON body of the response. The body is empty if Code is empty.
+	Code    string
+	Message string
+
+	// Details are the field-level details of the validation errors.
+	Details []corpbankclient.FieldError
+
+	// Header is added to the response, e.g. `Retry-After`.
+	Header http.Header
+
+	// Times is the number of the requests to fail. Defaults to 1, negative values fail forever.
+	Times int
+}
+
+type apiKey struct {
+	corpbankclient.APIKey
+	secret []byte
+}
+
+type payment struct {
+	corpbankclient.Payment
+	account *Account
+	trx     corpbankclient.Transaction
+}
+
+type idempotentPayment struct {
+	reqBody []byte
+	result  corpbankclient.PaymentResult
+}
+
+// Server is a fake of the corporate banking API, backed by an httptest.Server.
+// It verifies the bearer tokens in the same way as the real service.
+type Server struct {
+	*httptest.Server
+
+	// MaxTimeDiff is the allowed clock skew of the bearer token timestamps.
+	MaxTimeDiff time.Duration
+
+	// AutoSettlePayments marks the new payments as sent immediately. Otherwise, the payments
+	// remain pending until SetPaymentStatus is called. Defaults to true.
+	AutoSettlePayments bool
+
+	mu           sync.Mutex
+	creds        corpbankclient.Credentials
+	user         corpbankclient.AuthUser
+	keys         []*apiKey
+	accounts     []*Account
+	transactions []corpbankclient.Transaction
+	payments     []*payment
+	idempotency  map[string]*idempotentPayment
+	injections   []*ErrorInjection
+}
+
+// NewServer starts and returns a new fake server with a single enabled API key.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		MaxTimeDiff:        defaultMaxTimeDiff,
+		AutoSettlePayments: true,
+		user: corpbankclient.AuthUser{
+			Email:     "test@example.com",
+			FirstName: "Test",
+			LastName:  "User",
+			Status:    corpbankclient.AuthUserStatusActive,
+		},
+		idempotency: map[string]*idempotentPayment{},
+	}
+
+	s.creds = s.newAPIKey()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Credentials returns the credentials of the initial API key.
+func (s *Server) Credentials() corpbankclient.Credentials {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.creds
+}
+
+// NewClient returns a client connected to the fake server with the initial API key.
+// The API base URL and the HTTP client of the given options are overridden.
+func (s *Server) NewClient(clientOpts *corpbankclient.ClientOptions) (*corpbankclient.Client, error) {
+	opts := corpbankclient.ClientOptions{}
+	if clientOpts != nil {
+		opts = *clientOpts
+	}
+
+	opts.APIBaseURL = s.URL
+	opts.HTTPClient = s.Client()
+
+	return corpbankclient.NewClient(s.Credentials(), &opts)
+}
+
+// SetUser replaces the details of the authenticated user.
+func (s *Server) SetUser(user corpbankclient.AuthUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.user = user
+}
+
+// AddAccount adds a bank account with the given IBAN and balance, and returns the account ID.
+func (s *Server) AddAccount(iban string, balance decimal.Decimal) uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc := &Account{
+		ID:            uuid.New(),
+		IBAN:          iban,
+		BankCode:      bankCode(iban),
+		Currency:      defaultCurrency,
+		Balance:       balance,
+		LastUpdatedAt: time.Now().UTC(),
+	}
+
+	s.accounts = append(s.accounts, acc)
+
+	return acc.ID
+}
+
+// Account returns a copy of the account by the given ID.
+func (s *Server) Account(accountID uuid.UUID) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if acc := s.accountByID(accountID); acc != nil {
+		return *acc, true
+	}
+
+	return Account{}, false
+}
+
+// AddTransaction adds a bank transaction to be listed. The ID and the dates are generated if they are empty.
+func (s *Server) AddTransaction(trx corpbankclient.Transaction) corpbankclient.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addTransaction(trx)
+}
+
+// Transactions returns all the bank transactions held by the fake server, the latest first.
+func (s *Server) Transactions() []corpbankclient.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]corpbankclient.Transaction(nil), s.transactions...)
+}
+
+// InjectError makes the fake server fail the matching requests with the given error.
+// The injected errors are matched in the order they were added.
+func (s *Server) InjectError(e ErrorInjection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.StatusCode == 0 {
+		e.StatusCode = http.StatusUnprocessableEntity
+	}
+
+	if e.Times == 0 {
+		e.Times = 1
+	}
+
+	s.injections = append(s.injections, &e)
+}
+
+// FailNextPayment makes the fake server fail the next payment with the given error code,
+// e.g. CodeInsufficientBalance.
+func (s *Server) FailNextPayment(code string) {
+	s.InjectError(ErrorInjection{
+		Method: http.MethodPost,
+		Path:   "/payments",
+		Code:   code,
+	})
+}
+
+func (s *Server) newAPIKey() corpbankclient.Credentials {
+	secret := []byte(uuid.NewString())
+	encSecret := base64.StdEncoding.EncodeToString(secret)
+
+	k := &apiKey{
+		APIKey: corpbankclient.APIKey{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			Enabled:   true,
+			Secret:    &encSecret,
+		},
+		secret: secret,
+	}
+
+	s.keys = append(s.keys, k)
+
+	return corpbankclient.Credentials{
+		APIKeyID:     k.ID.String(),
+		APIKeySecret: encSecret,
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxReadBytes))
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("X-Request-ID", uuid.NewString())
+
+	if err := s.authenticate(r, body); err != nil {
+		writeErr(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+		return
+	}
+
+	if s.injectedErr(w, r) {
+		return
+	}
+
+	seg := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(seg) == 1 && seg[0] == "me":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"userAccount": s.user})
+
+	case r.Method == http.MethodGet && len(seg) == 3 && seg[0] == "accounts" && seg[2] == "balance":
+		s.handleBalance(w, seg[1])
+
+	case r.Method == http.MethodGet && len(seg) == 1 && seg[0] == "api-keys":
+		s.handleAPIKeys(w, r)
+
+	case r.Method == http.MethodPost && len(seg) == 1 && seg[0] == "api-keys":
+		creds := s.newAPIKey()
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"apiKey": s.keyByID(uuid.MustParse(creds.APIKeyID)).APIKey})
+
+	case r.Method == http.MethodDelete && len(seg) == 2 && seg[0] == "api-keys":
+		s.handleDelAPIKey(w, seg[1])
+
+	case r.Method == http.MethodPut && len(seg) == 3 && seg[0] == "api-keys" && seg[2] == "enabled":
+		s.handleEnableAPIKey(w, seg[1], body)
+
+	case r.Method == http.MethodGet && len(seg) == 1 && seg[0] == "bank-transactions":
+		s.handleTransactions(w, r)
+
+	case r.Method == http.MethodPost && len(seg) == 1 && seg[0] == "payments":
+		s.handlePayment(w, r, body)
+
+	case r.Method == http.MethodGet && len(seg) == 2 && seg[0] == "payments":
+		s.handleGetPayment(w, seg[1])
+
+	default:
+		writeErr(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) authenticate(r *http.Request, body []byte) error {
+	hdr := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(hdr) < 7 || strings.ToLower(hdr[:7]) != "bearer " {
+		return errors.New("missing bearer token")
+	}
+
+	token := &corpbankclient.BearerToken{}
+	if err := token.Unpack(strings.TrimSpace(hdr[7:])); err != nil {
+		return errors.WithStack(err)
+	}
+
+	k := s.keyByID(token.APIKeyID)
+	if k == nil || !k.Enabled {
+		return errors.Errorf("unknown or disabled API key: %s", token.APIKeyID)
+	}
+
+	if err := token.VerifyRequest(k.secret, r, body, s.MaxTimeDiff); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (s *Server) injectedErr(w http.ResponseWriter, r *http.Request) bool {
+	for i, e := range s.injections {
+		if (e.Method != "" && e.Method != r.Method) || (e.Path != "" && e.Path != r.URL.Path) {
+			continue
+		}
+
+		if e.Times > 0 {
+			if e.Times--; e.Times == 0 {
+				s.injections = append(s.injections[:i], s.injections[i+1:]...)
+			}
+		}
+
+		for k, v := range e.Header {
+			w.Header()[k] = v
+		}
+
+		if e.Code == "" {
+			w.WriteHeader(e.StatusCode)
+			return true
+		}
+
+		writeJSON(w, e.StatusCode, &corpbankclient.Error{
+			Code:    e.Code,
+			Message: e.Message,
+			Details: e.Details,
+		})
+
+		return true
+	}
+
+	return false
+}
+
+func (s *Server) handleBalance(w http.ResponseWriter, accountID string) {
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	acc := s.accountByID(id)
+	if acc == nil {
+		writeErr(w, http.StatusNotFound, CodeAccountNotFound, fmt.Sprintf("account not found: %s", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &corpbankclient.AccountBalance{
+		Balance:       acc.Balance,
+		LastUpdatedAt: acc.LastUpdatedAt,
+	})
+}
+
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	pageNum, pageSize, err := pagination(r)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	keys := make([]corpbankclient.APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		key := k.APIKey
+		key.Secret = nil
+		keys = append(keys, key)
+	}
+
+	from, to, totalPages := paginate(len(keys), pageNum, pageSize)
+
+	resp := map[string]interface{}{
+		"pagination": map[string]int{
+			"pageNum":      pageNum,
+			"pageSize":     pageSize,
+			"totalPages":   totalPages,
+			"totalRecords": len(keys),
+		},
+		"apiKeys": keys[from:to],
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleDelAPIKey(w http.ResponseWriter, apiKeyID string) {
+	id, err := uuid.Parse(apiKeyID)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	for i, k := range s.keys {
+		if k.ID == id {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	writeErr(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("API key not found: %s", id))
+}
+
+func (s *Server) handleEnableAPIKey(w http.ResponseWriter, apiKeyID string, body []byte) {
+	id, err := uuid.Parse(apiKeyID)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	req := struct {
+		Enabled *bool `json:"enabled"`
+	}{}
+
+	if err := json.Unmarshal(body, &req); err != nil || req.Enabled == nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, "missing `enabled` field")
+		return
+	}
+
+	k := s.keyByID(id)
+	if k == nil {
+		writeErr(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("API key not found: %s", id))
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	k.Enabled = *req.Enabled
+	k.ModifiedAt = &now
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	pageNum, pageSize, err := pagination(r)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+
+	var startDate, endDate time.Time
+
+	if v := q.Get("startDate"); v != "" {
+		if startDate, err = time.Parse(time.RFC3339, v); err != nil {
+			writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+			return
+		}
+	}
+
+	if v := q.Get("endDate"); v != "" {
+		if endDate, err = time.Parse(time.RFC3339, v); err != nil {
+			writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+			return
+		}
+	}
+
+	ibans := map[string]bool{}
+	for _, v := range q["account"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+			return
+		}
+
+		if acc := s.accountByID(id); acc != nil {
+			ibans[acc.IBAN] = true
+		} else {
+			ibans[""] = true
+		}
+	}
+
+	direction := corpbankclient.TrxDirection(q.Get("direction"))
+
+	trxs := []corpbankclient.Transaction{}
+	for _, t := range s.transactions {
+		if !startDate.IsZero() && t.Date.Before(startDate) {
+			continue
+		}
+
+		if !endDate.IsZero() && t.Date.After(endDate) {
+			continue
+		}
+
+		if direction != "" && t.Direction != direction {
+			continue
+		}
+
+		if len(ibans) > 0 && !ibans[t.Account.IBAN] {
+			continue
+		}
+
+		trxs = append(trxs, t)
+	}
+
+	from, to, totalPages := paginate(len(trxs), pageNum, pageSize)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"page_num":      pageNum,
+		"total_pages":   totalPages,
+		"total_records": len(trxs),
+		"transactions":  trxs[from:to],
+	})
+}
+
+type paymentAddr struct {
+	AddrType string `json:"addressType"`
+	Addr     string `json:"address"`
+}
+
+type paymentReq struct {
+	Src paymentAddr `json:"source"`
+	Dst struct {
+		Addr paymentAddr `json:"address"`
+		ID   *struct {
+			IDType string `json:"identifierType"`
+			ID     string `json:"identifier"`
+		} `json:"identifier"`
+		Name string `json:"name"`
+	} `json:"destination"`
+	Date     string `json:"date"`
+	Amount   string `json:"amount"`
+	RefCode  string `json:"refNum"`
+	Desc     string `json:"description"`
+	Callback string `json:"callbackURL"`
+	Method   string `json:"transferMethod"`
+}
+
+func (s *Server) handlePayment(w http.ResponseWriter, r *http.Request, body []byte) {
+	idempotencyKey := r.Header.Get("X-Idempotency-Key")
+
+	if p, ok := s.idempotency[idempotencyKey]; ok && idempotencyKey != "" {
+		if !bytes.Equal(p.reqBody, body) {
+			writeErr(w, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "the idempotency key is already used for a different payment")
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, p.result)
+		return
+	}
+
+	req := &paymentReq{}
+	if err := json.Unmarshal(body, req); err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	var details []corpbankclient.FieldError
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		details = append(details, corpbankclient.FieldError{
+			Field:   "amount",
+			Code:    "INVALID_AMOUNT",
+			Message: fmt.Sprintf("invalid amount: `%s`", req.Amount),
+		})
+	}
+
+	if _, err := time.Parse(paymentDateLayout, req.Date); err != nil {
+		details = append(details, corpbankclient.FieldError{
+			Field:   "date",
+			Code:    "INVALID_DATE",
+			Message: fmt.Sprintf("invalid date: `%s`", req.Date),
+		})
+	}
+
+	if len(details) > 0 {
+		writeJSON(w, http.StatusBadRequest, &corpbankclient.Error{
+			Code:    CodeValidationError,
+			Message: "invalid payment order",
+			Details: details,
+		})
+		return
+	}
+
+	if req.Dst.Name == "" || req.Dst.Addr.Addr == "" {
+		writeErr(w, http.StatusUnprocessableEntity, CodeIncorrectRecipientData, "missing recipient name or address")
+		return
+	}
+
+	// the easy address aliases are resolved to their owners, so the identifier is optional
+	if req.Dst.ID == nil || req.Dst.ID.ID == "" {
+		if corpbankclient.AddrType(req.Dst.Addr.AddrType) == corpbankclient.AddrTypeIBAN {
+			writeErr(w, http.StatusUnprocessableEntity, CodeInvalidRecipientID, "missing recipient identifier")
+			return
+		}
+	} else {
+		switch corpbankclient.RecipientIDType(req.Dst.ID.IDType) {
+		case corpbankclient.RecipientIDTypeNationalID, corpbankclient.RecipientIDTypeTaxID,
+			corpbankclient.RecipientIDTypeForeignID, corpbankclient.RecipientIDTypePassport:
+		default:
+			writeErr(w, http.StatusUnprocessableEntity, CodeInvalidRecipientID, fmt.Sprintf("unknown identifier type: `%s`", req.Dst.ID.IDType))
+			return
+		}
+	}
+
+	acc := s.accountByIBAN(req.Src.Addr)
+	if acc == nil {
+		writeErr(w, http.StatusNotFound, CodeAccountNotFound, fmt.Sprintf("account not found: %s", req.Src.Addr))
+		return
+	}
+
+	if acc.Balance.LessThan(amount) {
+		writeErr(w, http.StatusUnprocessableEntity, CodeInsufficientBalance, "insufficient balance")
+		return
+	}
+
+	recipient := &corpbankclient.TransactionParticipant{
+		Name: req.Dst.Name,
+	}
+
+	if req.Dst.ID != nil {
+		recipient.IdentityNumber = req.Dst.ID.ID
+	}
+
+	transferMethod := corpbankclient.TrxTransferMethodEFT
+
+	switch corpbankclient.AddrType(req.Dst.Addr.AddrType) {
+	case corpbankclient.AddrTypeIBAN:
+		recipient.IBAN = req.Dst.Addr.Addr
+		recipient.BankCode = bankCode(req.Dst.Addr.Addr)
+
+		if recipient.BankCode == acc.BankCode {
+			transferMethod = corpbankclient.TrxTransferMethodHavale
+		}
+
+	case corpbankclient.AddrTypePhone, corpbankclient.AddrTypeEmail, corpbankclient.AddrTypeNationalID:
+		// the easy address aliases are resolved by the FAST system
+		transferMethod = corpbankclient.TrxTransferMethodFAST
+
+	default:
+		writeErr(w, http.StatusUnprocessableEntity, CodeIncorrectRecipientData, fmt.Sprintf("unknown address type: `%s`", req.Dst.Addr.AddrType))
+		return
+	}
+
+	switch m := corpbankclient.TrxTransferMethod(req.Method); m {
+	case "":
+	case corpbankclient.TrxTransferMethodHavale:
+		if transferMethod != corpbankclient.TrxTransferMethodHavale {
+			writeErr(w, http.StatusUnprocessableEntity, CodeIncorrectRecipientData, "HAVALE requires the recipient in the same bank")
+			return
+		}
+
+	case corpbankclient.TrxTransferMethodEFT, corpbankclient.TrxTransferMethodFAST:
+		transferMethod = m
+
+	default:
+		writeErr(w, http.StatusBadRequest, CodeValidationError, fmt.Sprintf("unknown transfer method: `%s`", req.Method))
+		return
+	}
+
+	now := time.Now().UTC()
+
+	acc.Balance = acc.Balance.Sub(amount)
+	acc.LastUpdatedAt = now
+
+	p := &payment{
+		Payment: corpbankclient.Payment{
+			ID:        uuid.New(),
+			Status:    corpbankclient.PaymentStatusPending,
+			Amount:    amount,
+			RefCode:   req.RefCode,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		account: acc,
+		trx: corpbankclient.Transaction{
+			Account:        corpbankclient.TransactionAccount{BankCode: acc.BankCode, IBAN: acc.IBAN},
+			Amount:         amount,
+			Currency:       acc.Currency,
+			Direction:      corpbankclient.TrxDirectionOutgoing,
+			Description:    req.Desc,
+			RefCode:        req.RefCode,
+			TransferMethod: transferMethod,
+			Sender:         &corpbankclient.TransactionParticipant{BankCode: acc.BankCode, IBAN: acc.IBAN},
+			Recipient:      recipient,
+		},
+	}
+
+	p.trx.PaymentID = &p.ID
+
+	s.payments = append(s.payments, p)
+
+	if s.AutoSettlePayments {
+		s.setPaymentStatus(p, corpbankclient.PaymentStatusSent, "")
+	}
+
+	result := corpbankclient.PaymentResult{PaymentID: p.ID}
+
+	if idempotencyKey != "" {
+		s.idempotency[idempotencyKey] = &idempotentPayment{reqBody: body, result: result}
+	}
+
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+func (s *Server) handleGetPayment(w http.ResponseWriter, paymentID string) {
+	id, err := uuid.Parse(paymentID)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	p := s.paymentByID(id)
+	if p == nil {
+		writeErr(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("payment not found: %s", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &p.Payment)
+}
+
+// SetPaymentStatus changes the status of the payment. The bank transaction of the payment
+// is created when it is sent, and the amount is refunded when it fails or is rejected.
+func (s *Server) SetPaymentStatus(paymentID uuid.UUID, status corpbankclient.PaymentStatus, failureReason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.paymentByID(paymentID)
+	if p == nil {
+		return errors.Errorf("payment not found: %s", paymentID)
+	}
+
+	if p.Status.IsTerminal() {
+		return errors.Errorf("payment is already in a terminal state: %s", p.Status)
+	}
+
+	s.setPaymentStatus(p, status, failureReason)
+
+	return nil
+}
+
+// Payment returns a copy of the payment by the given ID.
+func (s *Server) Payment(paymentID uuid.UUID) (corpbankclient.Payment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.paymentByID(paymentID); p != nil {
+		return p.Payment, true
+	}
+
+	return corpbankclient.Payment{}, false
+}
+
+func (s *Server) setPaymentStatus(p *payment, status corpbankclient.PaymentStatus, failureReason string) {
+	now := time.Now().UTC()
+
+	p.Status = status
+	p.FailureReason = failureReason
+	p.UpdatedAt = now
+
+	switch status {
+	case corpbankclient.PaymentStatusSent:
+		p.ExecutedAt = &now
+		p.trx.Date = now
+		p.trx.ReceivedAt = now
+		s.addTransaction(p.trx)
+
+	case corpbankclient.PaymentStatusFailed, corpbankclient.PaymentStatusRejected:
+		p.account.Balance = p.account.Balance.Add(p.Amount)
+		p.account.LastUpdatedAt = now
+	}
+}
+
+func (s *Server) paymentByID(id uuid.UUID) *payment {
+	for _, p := range s.payments {
+		if p.ID == id {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) addTransaction(trx corpbankclient.Transaction) corpbankclient.Transaction {
+	if trx.ID == uuid.Nil {
+		trx.ID = uuid.New()
+	}
+
+	if trx.Date.IsZero() {
+		trx.Date = time.Now().UTC()
+	}
+
+	if trx.ReceivedAt.IsZero() {
+		trx.ReceivedAt = trx.Date
+	}
+
+	if trx.Currency == "" {
+		trx.Currency = defaultCurrency
+	}
+
+	s.transactions = append(s.transactions, trx)
+
+	sort.SliceStable(s.transactions, func(i, j int) bool {
+		return s.transactions[i].Date.After(s.transactions[j].Date)
+	})
+
+	return trx
+}
+
+func (s *Server) keyByID(id uuid.UUID) *apiKey {
+	for _, k := range s.keys {
+		if k.ID == id {
+			return k
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) accountByID(id uuid.UUID) *Account {
+	for _, acc := range s.accounts {
+		if acc.ID == id {
+			return acc
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) accountByIBAN(iban string) *Account {
+	for _, acc := range s.accounts {
+		if acc.IBAN == iban {
+			return acc
+		}
+	}
+
+	return nil
+}
+
+// bankCode returns the bank code part of a Turkish IBAN.
+func bankCode(accountIBAN string) string {
+	code, _ := iban.BankCode(accountIBAN)
+	return code
+}
+
+func pagination(r *http.Request) (int, int, error) {
+	pageNum, pageSize := 1, defaultPageSize
+
+	q := r.URL.Query()
+
+	if v := q.Get("pageNum"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, errors.Errorf("invalid page number: `%s`", v)
+		}
+
+		pageNum = n
+	}
+
+	if v := q.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, errors.Errorf("invalid page size: `%s`", v)
+		}
+
+		pageSize = n
+	}
+
+	return pageNum, pageSize, nil
+}
+
+// paginate returns the slice bounds of the requested page and the total number of pages.
+func paginate(total, pageNum, pageSize int) (int, int, int) {
+	totalPages := (total + pageSize - 1) / pageSize
+
+	from := (pageNum - 1) * pageSize
+	if from > total {
+		from = total
+	}
+
+	to := from + pageSize
+	if to > total {
+		to = total
+	}
+
+	return from, to, totalPages
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, statusCode int, code, message string) {
+	writeJSON(w, statusCode, &corpbankclient.Error{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,361 @@
+package corpbanktest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testSenderIBAN    = "TR330006100519786457841326"
+	testRecipientIBAN = "TR400006200000000000000001"
+)
+
+// countingTransport counts the requests sent to the fake server, by their methods and paths.
+type countingTransport struct {
+	rt http.RoundTripper
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.counts[req.Method+" "+req.URL.Path]++
+	t.mu.Unlock()
+
+	return t.rt.RoundTrip(req)
+}
+
+func (t *countingTransport) count(method, path string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.counts[method+" "+path]
+}
+
+// newCountingClient returns a client of the fake server, which counts its requests.
+func newCountingClient(t *testing.T, s *Server, clientOpts corpbankclient.ClientOptions) (*corpbankclient.Client, *countingTransport) {
+	t.Helper()
+
+	hc := s.Client()
+	ct := &countingTransport{rt: hc.Transport, counts: map[string]int{}}
+
+	clientOpts.APIBaseURL = s.URL
+	clientOpts.HTTPClient = &http.Client{Transport: ct}
+
+	client, err := corpbankclient.NewClient(s.Credentials(), &clientOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, ct
+}
+
+func testOrder(refCode string) corpbankclient.PaymentOrder {
+	return corpbankclient.PaymentOrder{
+		SenderIBAN:           testSenderIBAN,
+		RecipientIBAN:        testRecipientIBAN,
+		RecipientName:        "Ali Veli",
+		RecipientIdentityNum: "10000000146",
+		TransferAmount:       decimal.NewFromInt(10),
+		RefCode:              refCode,
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := &corpbankclient.RetryPolicy{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     100 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name      string
+		injection ErrorInjection
+		wantCalls int
+		wantErr   bool
+		sentinel  error
+		minWait   time.Duration
+	}{
+		{
+			name:      "recovered",
+			injection: ErrorInjection{StatusCode: http.StatusServiceUnavailable, Times: 2},
+			wantCalls: 3,
+		},
+		{
+			name:      "exhausted",
+			injection: ErrorInjection{StatusCode: http.StatusServiceUnavailable, Times: 3},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			// the requested wait is capped by MaxInterval, but it is still longer than the backoff
+			name: "Retry-After",
+			injection: ErrorInjection{
+				StatusCode: http.StatusTooManyRequests,
+				Code:       CodeRateLimitExceeded,
+				Header:     http.Header{"Retry-After": {"60"}},
+			},
+			wantCalls: 2,
+			minWait:   policy.MaxInterval,
+		},
+		{
+			name:      "permanent error",
+			injection: ErrorInjection{StatusCode: http.StatusBadRequest, Code: CodeValidationError},
+			wantCalls: 1,
+			wantErr:   true,
+			sentinel:  corpbankclient.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			defer s.Close()
+
+			client, ct := newCountingClient(t, s, corpbankclient.ClientOptions{RetryPolicy: policy})
+
+			tt.injection.Method = http.MethodGet
+			tt.injection.Path = "/me"
+			s.InjectError(tt.injection)
+
+			start := time.Now()
+			_, err := client.Me(context.Background())
+			elapsed := time.Since(start)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Me() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Fatalf("Me() = %v, want %v", err, tt.sentinel)
+			}
+
+			if got := ct.count(http.MethodGet, "/me"); got != tt.wantCalls {
+				t.Fatalf("%d requests, want %d", got, tt.wantCalls)
+			}
+
+			if elapsed < tt.minWait || elapsed > 10*time.Second {
+				t.Fatalf("Me() returned in %s, want at least %s", elapsed, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestRetryPayment(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.AddAccount(testSenderIBAN, decimal.NewFromInt(1000))
+
+	client, ct := newCountingClient(t, s, corpbankclient.ClientOptions{
+		RetryPolicy: &corpbankclient.RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond},
+	})
+
+	// the payments are retried only with an idempotency key
+	s.InjectError(ErrorInjection{Method: http.MethodPost, Path: "/payments", StatusCode: http.StatusServiceUnavailable})
+
+	if _, err := client.MakePayment(context.Background(), testOrder("R1")); err == nil {
+		t.Fatal("MakePayment() without an idempotency key = nil, want an error")
+	}
+
+	if got := ct.count(http.MethodPost, "/payments"); got != 1 {
+		t.Fatalf("%d requests without an idempotency key, want 1", got)
+	}
+
+	s.InjectError(ErrorInjection{Method: http.MethodPost, Path: "/payments", StatusCode: http.StatusServiceUnavailable})
+
+	o := testOrder("R2")
+	o.IdempotencyKey = "key-2"
+
+	if _, err := client.MakePayment(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ct.count(http.MethodPost, "/payments"); got != 3 {
+		t.Fatalf("%d requests, want 3", got)
+	}
+}
+
+func TestTransactionIterator(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	for i := 0; i < 6; i++ {
+		s.AddTransaction(corpbankclient.Transaction{
+			Amount:    decimal.NewFromInt(int64(i + 1)),
+			Direction: corpbankclient.TrxDirectionIncoming,
+			RefCode:   fmt.Sprintf("R%d", i),
+		})
+	}
+
+	client, ct := newCountingClient(t, s, corpbankclient.ClientOptions{})
+	requests := func() int { return ct.count(http.MethodGet, "/bank-transactions") }
+
+	t.Run("all pages", func(t *testing.T) {
+		before := requests()
+
+		it := client.TransactionIterator(context.Background(), corpbankclient.WithPageSize(2))
+		defer it.Close()
+
+		seen := map[string]bool{}
+		for it.Next() {
+			seen[it.Transaction().RefCode] = true
+		}
+
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(seen) != 6 {
+			t.Fatalf("%d transactions, want 6", len(seen))
+		}
+
+		if got := requests() - before; got != 3 {
+			t.Fatalf("%d requests, want 3", got)
+		}
+	})
+
+	t.Run("prefetch and close", func(t *testing.T) {
+		before := requests()
+
+		it := client.TransactionIterator(context.Background(), corpbankclient.WithPageSize(2))
+
+		if !it.Next() {
+			t.Fatal(it.Err())
+		}
+
+		// the next page is prefetched in the background, while the first page is consumed
+		deadline := time.Now().Add(5 * time.Second)
+		for requests()-before < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+
+		// and only one page is fetched ahead
+		time.Sleep(50 * time.Millisecond)
+
+		if got := requests() - before; got != 2 {
+			t.Fatalf("%d requests after the first transaction, want 2", got)
+		}
+
+		it.Close()
+		it.Close()
+
+		if it.Next() {
+			t.Fatal("Next() = true after Close")
+		}
+
+		if err := it.Err(); err != nil {
+			t.Fatalf("Err() = %v after Close, want nil", err)
+		}
+
+		if got := requests() - before; got != 2 {
+			t.Fatalf("%d requests after Close, want 2", got)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s.InjectError(ErrorInjection{Method: http.MethodGet, Path: "/bank-transactions", StatusCode: http.StatusForbidden, Code: CodeForbidden})
+
+		it := client.TransactionIterator(context.Background(), corpbankclient.WithPageSize(2))
+		defer it.Close()
+
+		if it.Next() {
+			t.Fatal("Next() = true, want false")
+		}
+
+		if err := it.Err(); !errors.Is(err, corpbankclient.ErrForbidden) {
+			t.Fatalf("Err() = %v, want ErrForbidden", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		it := client.TransactionIterator(ctx, corpbankclient.WithPageSize(2))
+		defer it.Close()
+
+		if !it.Next() {
+			t.Fatal(it.Err())
+		}
+
+		cancel()
+
+		for it.Next() {
+		}
+
+		if err := it.Err(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Err() = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestBatchResume(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.AddAccount(testSenderIBAN, decimal.NewFromInt(1000))
+
+	client, ct := newCountingClient(t, s, corpbankclient.ClientOptions{})
+
+	orders := []corpbankclient.PaymentOrder{testOrder("R1"), testOrder("R2"), testOrder("R3")}
+
+	if _, err := client.MakePayments(context.Background(), orders, corpbankclient.BatchOptions{}); !errors.Is(err, corpbankclient.ErrInvalidPaymentOrder) {
+		t.Fatalf("MakePayments() without a batch ID = %v, want ErrInvalidPaymentOrder", err)
+	}
+
+	opts := corpbankclient.BatchOptions{
+		Parallelism: 1,
+		BatchID:     "payroll-2024-01",
+		Checkpoint:  &corpbankclient.FileCheckpointStore{Path: filepath.Join(t.TempDir(), "checkpoint.jsonl")},
+	}
+
+	// the first payment fails in the first run
+	s.FailNextPayment(CodeInsufficientBalance)
+
+	first, err := client.MakePayments(context.Background(), orders, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Succeeded() != 2 || !errors.Is(first.Items[0].Err, corpbankclient.ErrInsufficientBalance) {
+		t.Fatalf("%d succeeded, the first error is %v, want 2 and ErrInsufficientBalance", first.Succeeded(), first.Items[0].Err)
+	}
+
+	if got := ct.count(http.MethodPost, "/payments"); got != 3 {
+		t.Fatalf("%d requests in the first run, want 3", got)
+	}
+
+	// only the failed payment is sent again
+	second, err := client.MakePayments(context.Background(), orders, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ct.count(http.MethodPost, "/payments"); got != 4 {
+		t.Fatalf("%d requests after the second run, want 4", got)
+	}
+
+	for i, item := range second.Items {
+		if item.Err != nil {
+			t.Fatalf("item %d: %v", i, item.Err)
+		}
+
+		if item.Resumed != (i > 0) {
+			t.Errorf("item %d: Resumed = %v, want %v", i, item.Resumed, i > 0)
+		}
+
+		if i > 0 && (item.IdempotencyKey != first.Items[i].IdempotencyKey || item.Result.PaymentID != first.Items[i].Result.PaymentID) {
+			t.Errorf("item %d is not resumed from the first run: %+v", i, item)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package corpbanktest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Tamper breaks the webhook request in a specific way, to test the rejection branches of the webhook handlers.
+type Tamper int
+
+const (
+	// TamperBadSignature corrupts the signature of the bearer token.
+	TamperBadSignature Tamper = iota + 1
+
+	// TamperSkewedTimestamp signs the request with a timestamp out of the allowed clock skew.
+	TamperSkewedTimestamp
+
+	// TamperWrongKeyID signs the request with the right secret, but a different API key ID.
+	TamperWrongKeyID
+
+	// TamperDuplicateAuthorization sends the `Authorization` header twice.
+	TamperDuplicateAuthorization
+
+	// TamperMissingAuthorization omits the `Authorization` header.
+	TamperMissingAuthorization
+)
+
+const defaultClockSkew = 2 * defaultMaxTimeDiff
+
+// WebhookSender produces correctly signed webhook notifications as the real service does,
+// and delivers them to a URL or an http.Handler.
+type WebhookSender struct {
+	// HTTPClient is used to deliver the notifications to a URL. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// ClockSkew is the timestamp offset used by TamperSkewedTimestamp. Defaults to 20 minutes.
+	ClockSkew time.Duration
+
+	// Algo is the signing scheme of the notifications. Defaults to corpbankclient.SigningAlgoHMACSHA256.
+	Algo corpbankclient.SigningAlgo
+
+	keyID  uuid.UUID
+	keySec []byte
+}
+
+// NewWebhookSender returns a webhook sender signing the notifications with the given credentials.
+func NewWebhookSender(apiCreds corpbankclient.Credentials) (*WebhookSender, error) {
+	apiKeyID, err := uuid.Parse(apiCreds.APIKeyID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse API key ID: `%s`", apiCreds.APIKeyID)
+	}
+
+	apiKeySec, err := base64.StdEncoding.DecodeString(apiCreds.APIKeySecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse API secret")
+	}
+
+	return &WebhookSender{
+		HTTPClient: http.DefaultClient,
+		ClockSkew:  defaultClockSkew,
+		keyID:      apiKeyID,
+		keySec:     apiKeySec,
+	}, nil
+}
+
+// NewRequest returns a signed webhook request with the JSON encoded payload, broken by the given tampers.
+func (s *WebhookSender) NewRequest(ctx context.Context, url string, payload interface{}, tampers ...Tamper) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to encode the webhook payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	token := &corpbankclient.BearerToken{
+		APIKeyID:  s.keyID,
+		Timestamp: time.Now(),
+		Algo:      s.Algo,
+	}
+
+	if hasTamper(tampers, TamperSkewedTimestamp) {
+		token.Timestamp = token.Timestamp.Add(-s.ClockSkew)
+	}
+
+	if hasTamper(tampers, TamperWrongKeyID) {
+		token.APIKeyID = uuid.New()
+	}
+
+	if err := token.SignRequest(s.keySec, req, body); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if hasTamper(tampers, TamperBadSignature) {
+		token.Signature[0] ^= 0xff
+	}
+
+	packed, err := token.Pack()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	hdr := fmt.Sprintf("Bearer %s", packed)
+
+	switch {
+	case hasTamper(tampers, TamperMissingAuthorization):
+
+	case hasTamper(tampers, TamperDuplicateAuthorization):
+		req.Header.Add("Authorization", hdr)
+		req.Header.Add("Authorization", hdr)
+
+	default:
+		req.Header.Set("Authorization", hdr)
+	}
+
+	return req, nil
+}
+
+// Send delivers the signed webhook notification with the given payload (e.g. a Transaction) to the URL.
+// The caller is responsible to close the response body.
+func (s *WebhookSender) Send(ctx context.Context, url string, payload interface{}, tampers ...Tamper) (*http.Response, error) {
+	req, err := s.NewRequest(ctx, url, payload, tampers...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return resp, nil
+}
+
+// Deliver delivers the signed webhook notification with the given payload (e.g. a Transaction)
+// directly to the handler, and returns the recorded response.
+func (s *WebhookSender) Deliver(h http.Handler, payload interface{}, tampers ...Tamper) (*http.Response, error) {
+	req, err := s.NewRequest(context.Background(), "http://webhook.test/", payload, tampers...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec.Result(), nil
+}
+
+// SendTransaction delivers a transaction notification to the URL.
+func (s *WebhookSender) SendTransaction(ctx context.Context, url string, trx corpbankclient.Transaction, tampers ...Tamper) (*http.Response, error) {
+	return s.Send(ctx, url, &trx, tampers...)
+}
+
+// DeliverTransaction delivers a transaction notification directly to the handler.
+func (s *WebhookSender) DeliverTransaction(h http.Handler, trx corpbankclient.Transaction, tampers ...Tamper) (*http.Response, error) {
+	return s.Deliver(h, &trx, tampers...)
+}
+
+// SendPaymentStatus delivers a payment status notification to the URL.
+func (s *WebhookSender) SendPaymentStatus(ctx context.Context, url string, payment corpbankclient.Payment, tampers ...Tamper) (*http.Response, error) {
+	event, err := newEvent(corpbankclient.WebhookEventPaymentStatus, &payment)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return s.Send(ctx, url, event, tampers...)
+}
+
+// DeliverPaymentStatus delivers a payment status notification directly to the handler.
+func (s *WebhookSender) DeliverPaymentStatus(h http.Handler, payment corpbankclient.Payment, tampers ...Tamper) (*http.Response, error) {
+	event, err := newEvent(corpbankclient.WebhookEventPaymentStatus, &payment)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return s.Deliver(h, event, tampers...)
+}
+
+func newEvent(eventType corpbankclient.WebhookEventType, data interface{}) (*corpbankclient.WebhookEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to encode the webhook event data")
+	}
+
+	return &corpbankclient.WebhookEvent{
+		Type: eventType,
+		Data: raw,
+	}, nil
+}
+
+func hasTamper(tampers []Tamper, t Tamper) bool {
+	for _, v := range tampers {
+		if v == t {
+			return true
+		}
+	}
+
+	return false
+}
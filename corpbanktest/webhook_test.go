@@ -0,0 +1,230 @@
+package corpbanktest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// webhookRecorder records the transactions passed to the webhook handler.
+type webhookRecorder struct {
+	mu   sync.Mutex
+	trxs []corpbankclient.Transaction
+}
+
+func (r *webhookRecorder) handle(ctx context.Context, trx corpbankclient.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.trxs = append(r.trxs, trx)
+
+	return nil
+}
+
+func (r *webhookRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.trxs)
+}
+
+func testTransaction() corpbankclient.Transaction {
+	return corpbankclient.Transaction{
+		ID:        uuid.New(),
+		Amount:    decimal.NewFromInt(10),
+		Direction: corpbankclient.TrxDirectionIncoming,
+		RefCode:   "R1",
+	}
+}
+
+func TestWebhookReplay(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	client, err := s.NewClient(&corpbankclient.ClientOptions{WebhookReplayStore: corpbankclient.NewMemoryReplayStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := NewWebhookSender(s.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &webhookRecorder{}
+	h := http.HandlerFunc(client.WebhookHandler(rec.handle))
+
+	trx := testTransaction()
+
+	req, err := sender.NewRequest(context.Background(), "http://webhook.test/", &trx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deliver sends the same signed request again
+	deliver := func() *http.Response {
+		r := req.Clone(context.Background())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Result()
+	}
+
+	if resp := deliver(); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("first delivery: %s, want %d", resp.Status, http.StatusAccepted)
+	}
+
+	resp := deliver()
+	msg, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(msg), "already been processed") {
+		t.Fatalf("replayed delivery: %s %s, want %d", resp.Status, msg, http.StatusOK)
+	}
+
+	// the redelivery of the same transaction is signed again, and it is recognized by the transaction ID
+	if resp, err := sender.DeliverTransaction(h, trx); err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Fatalf("redelivery: %s, want %d", resp.Status, http.StatusOK)
+	}
+
+	if resp, err := sender.DeliverTransaction(h, testTransaction()); err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("another transaction: %s, want %d", resp.Status, http.StatusAccepted)
+	}
+
+	if n := rec.count(); n != 2 {
+		t.Fatalf("the handler is called %d times, want 2", n)
+	}
+}
+
+func TestWebhookTampers(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	client, err := s.NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := NewWebhookSender(s.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &webhookRecorder{}
+	h := http.HandlerFunc(client.WebhookHandler(rec.handle))
+
+	tests := []struct {
+		name   string
+		tamper Tamper
+		want   int
+	}{
+		{"bad signature", TamperBadSignature, http.StatusForbidden},
+		{"skewed timestamp", TamperSkewedTimestamp, http.StatusForbidden},
+		{"wrong key ID", TamperWrongKeyID, http.StatusForbidden},
+		{"duplicate authorization", TamperDuplicateAuthorization, http.StatusBadRequest},
+		{"missing authorization", TamperMissingAuthorization, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := sender.DeliverTransaction(h, testTransaction(), tt.tamper)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("%s, want %d", resp.Status, tt.want)
+			}
+		})
+	}
+
+	if n := rec.count(); n != 0 {
+		t.Fatalf("the handler is called %d times, want 0", n)
+	}
+}
+
+func TestWebhookKeyRotation(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	client, err := s.NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldCreds := s.Credentials()
+
+	k, err := client.NewAPIKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCreds := corpbankclient.Credentials{APIKeyID: k.ID.String(), APIKeySecret: *k.Secret}
+
+	oldSender, err := NewWebhookSender(oldCreds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSender, err := NewWebhookSender(newCreds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &webhookRecorder{}
+	h := http.HandlerFunc(client.WebhookHandler(rec.handle))
+
+	deliver := func(sender *WebhookSender, want int) {
+		t.Helper()
+
+		resp, err := sender.DeliverTransaction(h, testTransaction())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != want {
+			t.Fatalf("%s, want %d", resp.Status, want)
+		}
+	}
+
+	// the new key is unknown until it is added to the keyring
+	deliver(oldSender, http.StatusAccepted)
+	deliver(newSender, http.StatusForbidden)
+
+	// both keys are accepted during the rotation
+	if err := client.WebhookVerifier().AddKey(newCreds); err != nil {
+		t.Fatal(err)
+	}
+
+	deliver(oldSender, http.StatusAccepted)
+	deliver(newSender, http.StatusAccepted)
+
+	// and the old key is rejected after it is removed
+	client.WebhookVerifier().RemoveKey(uuid.MustParse(oldCreds.APIKeyID))
+
+	deliver(oldSender, http.StatusForbidden)
+	deliver(newSender, http.StatusAccepted)
+
+	if n := rec.count(); n != 4 {
+		t.Fatalf("the handler is called %d times, want 4", n)
+	}
+}
@@ -0,0 +1,188 @@
+package corpbankclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CredentialsProvider resolves the API credentials. The client created by NewClientWithProvider
+// re-resolves the credentials periodically, by the CredentialsRefreshInterval of the client options,
+// and switches to the new API key when they change.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+const (
+	EnvAPIKeyID     = "CORPBANK_API_KEY_ID"
+	EnvAPIKeySecret = "CORPBANK_API_KEY_SECRET"
+)
+
+// EnvCredentialsProvider resolves the credentials from the environment variables.
+type EnvCredentialsProvider struct {
+	// KeyIDVar is the name of the API key ID variable. Defaults to CORPBANK_API_KEY_ID.
+	KeyIDVar string
+
+	// SecretVar is the name of the API key secret variable. Defaults to CORPBANK_API_KEY_SECRET.
+	SecretVar string
+}
+
+func (p *EnvCredentialsProvider) Credentials(ctx context.Context) (Credentials, error) {
+	keyIDVar, secretVar := p.KeyIDVar, p.SecretVar
+
+	if keyIDVar == "" {
+		keyIDVar = EnvAPIKeyID
+	}
+
+	if secretVar == "" {
+		secretVar = EnvAPIKeySecret
+	}
+
+	creds := Credentials{
+		APIKeyID:     os.Getenv(keyIDVar),
+		APIKeySecret: os.Getenv(secretVar),
+	}
+
+	if creds.APIKeyID == "" || creds.APIKeySecret == "" {
+		return Credentials{}, errors.Errorf("missing environment variables: `%s` or `%s`", keyIDVar, secretVar)
+	}
+
+	return creds, nil
+}
+
+type fileCredentials struct {
+	APIKeyID     string `json:"apiKeyID"`
+	APIKeySecret string `json:"apiKeySecret"`
+}
+
+// FileCredentialsProvider resolves the credentials from a JSON or YAML file, by its extension:
+//
+//	{"apiKeyID": "<API_KEY_ID>", "apiKeySecret": "<API_KEY_SECRET>"}
+//
+//	apiKeyID: <API_KEY_ID>
+//	apiKeySecret: <API_KEY_SECRET>
+//
+// The file is read again when its modification time or size changes, so the changes are picked up
+// by the next refresh of the client.
+type FileCredentialsProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   Credentials
+}
+
+func (p *FileCredentialsProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fi, err := os.Stat(p.Path)
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "unable to read the credentials file: `%s`", p.Path)
+	}
+
+	if p.creds.APIKeyID != "" && fi.ModTime().Equal(p.modTime) && fi.Size() == p.size {
+		return p.creds, nil
+	}
+
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "unable to read the credentials file: `%s`", p.Path)
+	}
+
+	fc := &fileCredentials{}
+
+	switch strings.ToLower(filepath.Ext(p.Path)) {
+	case ".yaml", ".yml":
+		err = parseYAMLCredentials(content, fc)
+	default:
+		err = json.Unmarshal(content, fc)
+	}
+
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "unable to parse the credentials file: `%s`", p.Path)
+	}
+
+	if fc.APIKeyID == "" || fc.APIKeySecret == "" {
+		return Credentials{}, errors.Errorf("missing `apiKeyID` or `apiKeySecret` in the credentials file: `%s`", p.Path)
+	}
+
+	p.creds = Credentials{
+		APIKeyID:     fc.APIKeyID,
+		APIKeySecret: fc.APIKeySecret,
+	}
+
+	p.modTime = fi.ModTime()
+	p.size = fi.Size()
+
+	return p.creds, nil
+}
+
+// parseYAMLCredentials parses the flat `key: value` mappings of a YAML document.
+func parseYAMLCredentials(content []byte, fc *fileCredentials) error {
+	s := bufio.NewScanner(bytes.NewReader(content))
+
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return errors.Errorf("invalid YAML mapping at line %d", lineNum)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		val := strings.TrimSpace(line[i+1:])
+
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		} else if j := strings.Index(val, " #"); j >= 0 {
+			val = strings.TrimSpace(val[:j])
+		}
+
+		switch key {
+		case "apiKeyID":
+			fc.APIKeyID = val
+		case "apiKeySecret":
+			fc.APIKeySecret = val
+		}
+	}
+
+	return errors.WithStack(s.Err())
+}
+
+// ChainCredentialsProvider resolves the credentials from the first provider which succeeds.
+type ChainCredentialsProvider []CredentialsProvider
+
+func (p ChainCredentialsProvider) Credentials(ctx context.Context) (Credentials, error) {
+	var errs []string
+
+	for _, provider := range p {
+		creds, err := provider.Credentials(ctx)
+		if err == nil {
+			return creds, nil
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	return Credentials{}, errors.Errorf("unable to resolve the credentials: [%s]", strings.Join(errs, "; "))
+}
+
+// StaticCredentialsProvider always resolves the same credentials.
+type StaticCredentialsProvider Credentials
+
+func (p StaticCredentialsProvider) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials(p), nil
+}
@@ -4,60 +4,229 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 )
 
-type errUnexpectedStatus struct {
-	StatusCode int
-	RespBody   []byte
+// Error is returned when the remote service responds with an unexpected status code.
+// It matches the sentinel errors (e.g. ErrInsufficientBalance) by errors.Is, according to its code.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"-"`
+
+	// Code and Message are parsed from the response body, they are empty if the body is not a JSON error.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// RequestID is the request or correlation ID of the response, to be reported to the service provider.
+	RequestID string `json:"-"`
+
+	// Details holds the field-level details of the validation errors.
+	Details []FieldError `json:"details"`
+
+	// Body is the raw response body, truncated to 4KB.
+	Body []byte `json:"-"`
+
+	sentinel error
 }
 
-type APIErr struct {
+// FieldError is the validation error of a single field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
 
+// ValidationError is returned when the remote service rejects the request due to invalid fields.
+// It matches ErrValidation by errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// RateLimitError is returned when the rate limit is exceeded. It matches ErrRateLimited by errors.Is.
+type RateLimitError struct {
+	// RetryAfter is the wait duration requested by the remote service, zero if it is unknown.
+	RetryAfter time.Duration
+}
+
+// APIErr is the former name of Error.
+//
+// Deprecated: use Error instead.
+type APIErr = Error
+
 var ErrCurrencyMismatch = errors.New("payment error: currency mismatch")
 var ErrIncorrectRecipientData = errors.New("payment error: incorrect recipient data")
 var ErrInsufficientBalance = errors.New("payment error: insufficient balance")
 var ErrInvalidRecipientID = errors.New("payment error: recipient id")
 var ErrOutOfEFTHours = errors.New("payment error: out of eft hours")
 
-func wrapErr(err error) error {
-	e := &errUnexpectedStatus{}
+var ErrUnauthorized = errors.New("auth error: unauthorized")
+var ErrForbidden = errors.New("auth error: forbidden")
+var ErrNotFound = errors.New("request error: not found")
+var ErrAccountNotFound = fmt.Errorf("request error: account not found: %w", ErrNotFound)
+var ErrIdempotencyKeyReused = errors.New("request error: idempotency key is reused with a different payload")
+var ErrRateLimited = errors.New("request error: rate limit exceeded")
+var ErrValidation = errors.New("request error: validation failed")
+
+// ErrInvalidPaymentOrder is returned by the client-side validation, before the payment order is sent.
+var ErrInvalidPaymentOrder = errors.New("payment error: invalid payment order")
+
+// permanentErrs are never retried, since they can not be recovered by sending the same request again.
+var permanentErrs = []error{
+	ErrCurrencyMismatch,
+	ErrIncorrectRecipientData,
+	ErrInsufficientBalance,
+	ErrInvalidRecipientID,
+	ErrOutOfEFTHours,
+	ErrInvalidPaymentOrder,
+	ErrUnauthorized,
+	ErrForbidden,
+	ErrNotFound,
+	ErrIdempotencyKeyReused,
+	ErrValidation,
+}
+
+var codeErrs = map[string]error{
+	"CURRENCY_MISMATCH":        ErrCurrencyMismatch,
+	"INCORRECT_RECIPIENT_DATA": ErrIncorrectRecipientData,
+	"INSUFFICIENT_BALANCE":     ErrInsufficientBalance,
+	"INVALID_RECIPIENT_ID":     ErrInvalidRecipientID,
+	"OUT_OF_EFT_HOURS":         ErrOutOfEFTHours,
+	"UNAUTHORIZED":             ErrUnauthorized,
+	"INVALID_SIGNATURE":        ErrUnauthorized,
+	"FORBIDDEN":                ErrForbidden,
+	"PERMISSION_DENIED":        ErrForbidden,
+	"NOT_FOUND":                ErrNotFound,
+	"ACCOUNT_NOT_FOUND":        ErrAccountNotFound,
+	"IDEMPOTENCY_KEY_REUSED":   ErrIdempotencyKeyReused,
+	"RATE_LIMIT_EXCEEDED":      ErrRateLimited,
+	"VALIDATION_ERROR":         ErrValidation,
+}
+
+// statusErrs are used when the response has no known error code.
+var statusErrs = map[int]error{
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrForbidden,
+	http.StatusNotFound:        ErrNotFound,
+	http.StatusTooManyRequests: ErrRateLimited,
+}
+
+var requestIDHeaders = []string{"X-Request-ID", "X-Correlation-ID", "X-Amzn-Trace-ID"}
+
+// newError parses the error response of the remote service.
+func newError(resp *http.Response, respBody []byte) *Error {
+	e := &Error{}
+
+	// the body is not always a JSON error, e.g. for the errors of the proxies
+	if err := json.Unmarshal(respBody, e); err != nil {
+		e.Code = ""
+		e.Message = ""
+		e.Details = nil
+	}
+
+	e.StatusCode = resp.StatusCode
+	e.Body = respBody
+
+	sentinel, ok := codeErrs[strings.ToUpper(e.Code)]
+	if !ok {
+		sentinel = statusErrs[resp.StatusCode]
+	}
+
+	switch sentinel {
+	case ErrValidation:
+		e.sentinel = &ValidationError{Fields: e.Details}
+
+	case ErrRateLimited:
+		wait, _ := retryAfter(resp.Header)
+		e.sentinel = &RateLimitError{RetryAfter: wait}
+
+	default:
+		e.sentinel = sentinel
+	}
+
+	for _, h := range requestIDHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			e.RequestID = v
+			break
+		}
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "HTTP %d", e.StatusCode)
+
+	if e.Code != "" {
+		fmt.Fprintf(&b, " %s", e.Code)
+	}
+
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	} else if e.Code == "" && len(e.Body) > 0 {
+		fmt.Fprintf(&b, ": %s", string(e.Body))
+	}
 
-	if !errors.As(err, &e) {
-		return err
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request ID: %s)", e.RequestID)
 	}
 
-	aErr := &APIErr{}
-	if err := json.Unmarshal(e.RespBody, &aErr); err != nil {
-		return err
+	return b.String()
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return ErrValidation.Error()
 	}
 
-	switch aErr.Code {
-	case "CURRENCY_MISMATCH":
-		return ErrCurrencyMismatch
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
 
-	case "INCORRECT_RECIPIENT_DATA":
-		return ErrIncorrectRecipientData
+	return fmt.Sprintf("%s: [%s]", ErrValidation.Error(), strings.Join(fields, "; "))
+}
 
-	case "INSUFFICIENT_BALANCE":
-		return ErrInsufficientBalance
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
 
-	case "INVALID_RECIPIENT_ID":
-		return ErrInvalidRecipientID
+// Field returns the validation error of the given field, or nil if the field is valid.
+func (e *ValidationError) Field(field string) *FieldError {
+	for i := range e.Fields {
+		if e.Fields[i].Field == field {
+			return &e.Fields[i]
+		}
+	}
 
-	case "OUT_OF_EFT_HOURS":
-		return ErrOutOfEFTHours
+	return nil
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: retry after %s", ErrRateLimited.Error(), e.RetryAfter)
 	}
 
-	return aErr
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
 }
 
-func (e *errUnexpectedStatus) Error() string {
-	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, string(e.RespBody))
+// Unwrap returns the sentinel error matching the error code, if any.
+func (e *Error) Unwrap() error {
+	return e.sentinel
 }
 
-func (e *APIErr) Error() string {
-	return fmt.Sprintf("APIErr %s: %s", e.Code, e.Message)
+// Retryable reports whether the same request may succeed if it is sent again later.
+func (e *Error) Retryable() bool {
+	if e.sentinel != nil && isPermanentErr(e.sentinel) {
+		return false
+	}
+
+	return retryableStatus(e.StatusCode)
 }
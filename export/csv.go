@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+// newCSVWriter returns a writer of the CSV files with a header line. The missing values are empty.
+func newCSVWriter(w io.Writer) *csvWriter {
+	cw := &csvWriter{
+		w:      csv.NewWriter(w),
+		record: make([]string, len(schema)),
+	}
+
+	// the errors of the buffered writer are returned by the next write or close
+	_ = cw.w.Write(Columns())
+
+	return cw
+}
+
+func (w *csvWriter) Write(trx corpbankclient.Transaction) error {
+	for i, c := range schema {
+		w.record[i] = formatText(c.value(&trx))
+	}
+
+	return errors.WithStack(w.w.Write(w.record))
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return errors.WithStack(w.w.Error())
+}
+
+// formatText formats the values of the text formats. The amounts are formatted with all of their
+// decimal places, and the dates in RFC 3339 with their time zones.
+func formatText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case decimal.Decimal:
+		return v.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,302 @@
+// Package export writes the bank transactions as CSV, JSON Lines or Parquet files with a stable
+// column schema, streaming them from the paginated transaction list.
+package export
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+)
+
+// Format is the file format of the export.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+const defaultRowGroupSize = 10000
+
+// Options customizes the export.
+type Options struct {
+	// Format is the file format. Defaults to CSV.
+	Format Format
+
+	// Gzip compresses the CSV and JSON Lines outputs as gzip streams. The Parquet files are not
+	// compressed as a whole, so that they remain readable, but their pages are compressed with gzip.
+	Gzip bool
+
+	// RowGroupSize is the number of rows per row group of the Parquet files. Defaults to 10000.
+	RowGroupSize int
+
+	// AmountScale is the number of decimal places of the amounts in the Parquet files, as their
+	// decimal columns have a fixed scale. The amounts with more decimal places are rejected
+	// instead of being rounded. It is a pointer so that zero can be set, and defaults to 2 if it is nil.
+	AmountScale *int
+}
+
+// Writer writes the transactions in a file format.
+type Writer interface {
+	// Write writes a transaction as a row.
+	Write(trx corpbankclient.Transaction) error
+
+	// Close writes the buffered rows and the trailer of the file format.
+	// It does not close the underlying writer.
+	Close() error
+}
+
+// columnType is the type of the values of a column.
+type columnType int
+
+const (
+	typeString columnType = iota
+	typeTimestamp
+	typeDecimal
+)
+
+// column is a column of the schema. The values are strings, time.Time or decimal.Decimal values,
+// by the type of the column, or nil if the value is missing in the optional columns.
+type column struct {
+	name     string
+	typ      columnType
+	optional bool
+	value    func(trx *corpbankclient.Transaction) interface{}
+}
+
+// schema is the stable list of the columns. The new columns must only be appended to the end.
+var schema = []column{
+	{"id", typeString, false, func(t *corpbankclient.Transaction) interface{} { return t.ID.String() }},
+	{"date", typeTimestamp, false, func(t *corpbankclient.Transaction) interface{} { return t.Date }},
+	{"received_at", typeTimestamp, true, func(t *corpbankclient.Transaction) interface{} { return optionalTime(t.ReceivedAt) }},
+	{"account_bank_code", typeString, false, func(t *corpbankclient.Transaction) interface{} { return t.Account.BankCode }},
+	{"account_iban", typeString, false, func(t *corpbankclient.Transaction) interface{} { return t.Account.IBAN }},
+	{"amount", typeDecimal, false, func(t *corpbankclient.Transaction) interface{} { return t.Amount }},
+	{"currency", typeString, false, func(t *corpbankclient.Transaction) interface{} { return t.Currency }},
+	{"direction", typeString, false, func(t *corpbankclient.Transaction) interface{} { return string(t.Direction) }},
+	{"transfer_method", typeString, false, func(t *corpbankclient.Transaction) interface{} { return string(t.TransferMethod) }},
+	{"reference_code", typeString, false, func(t *corpbankclient.Transaction) interface{} { return t.RefCode }},
+	{"description", typeString, false, func(t *corpbankclient.Transaction) interface{} { return t.Description }},
+	participantColumn("sender_bank_code", sender, func(p *corpbankclient.TransactionParticipant) string { return p.BankCode }),
+	participantColumn("sender_iban", sender, func(p *corpbankclient.TransactionParticipant) string { return p.IBAN }),
+	participantColumn("sender_identity_number", sender, func(p *corpbankclient.TransactionParticipant) string { return p.IdentityNumber }),
+	participantColumn("sender_name", sender, func(p *corpbankclient.TransactionParticipant) string { return p.Name }),
+	participantColumn("recipient_bank_code", recipient, func(p *corpbankclient.TransactionParticipant) string { return p.BankCode }),
+	participantColumn("recipient_iban", recipient, func(p *corpbankclient.TransactionParticipant) string { return p.IBAN }),
+	participantColumn("recipient_identity_number", recipient, func(p *corpbankclient.TransactionParticipant) string { return p.IdentityNumber }),
+	participantColumn("recipient_name", recipient, func(p *corpbankclient.TransactionParticipant) string { return p.Name }),
+	{"payment_id", typeString, true, func(t *corpbankclient.Transaction) interface{} {
+		if t.PaymentID == nil {
+			return nil
+		}
+
+		return t.PaymentID.String()
+	}},
+}
+
+func sender(t *corpbankclient.Transaction) *corpbankclient.TransactionParticipant {
+	return t.Sender
+}
+
+func recipient(t *corpbankclient.Transaction) *corpbankclient.TransactionParticipant {
+	return t.Recipient
+}
+
+// participantColumn flattens a field of the participant, which is missing if there is no participant.
+func participantColumn(
+	name string,
+	participant func(*corpbankclient.Transaction) *corpbankclient.TransactionParticipant,
+	field func(*corpbankclient.TransactionParticipant) string,
+) column {
+	return column{name, typeString, true, func(t *corpbankclient.Transaction) interface{} {
+		if p := participant(t); p != nil {
+			return field(p)
+		}
+
+		return nil
+	}}
+}
+
+func optionalTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
+
+// Columns returns the names of the columns, in their order in the files.
+func Columns() []string {
+	names := make([]string, len(schema))
+	for i, c := range schema {
+		names[i] = c.name
+	}
+
+	return names
+}
+
+// NewWriter returns a writer of the format of the options.
+func NewWriter(w io.Writer, opts *Options) (Writer, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	switch opts.Format {
+	case FormatParquet:
+		if s := opts.AmountScale; s != nil && (*s < 0 || *s > parquetDecimalPrecision) {
+			return nil, errors.Errorf("the amount scale must be between 0 and %d: %d", parquetDecimalPrecision, *s)
+		}
+
+		return newParquetWriter(w, opts), nil
+
+	case FormatCSV, "", FormatJSONL:
+		var gz *gzip.Writer
+		if opts.Gzip {
+			gz = gzip.NewWriter(w)
+			w = gz
+		}
+
+		if opts.Format == FormatJSONL {
+			return &gzipWriter{newJSONLWriter(w), gz}, nil
+		}
+
+		return &gzipWriter{newCSVWriter(w), gz}, nil
+
+	default:
+		return nil, errors.Errorf("unsupported export format: `%s`", opts.Format)
+	}
+}
+
+// gzipWriter closes the gzip stream of the text formats, if any, after the writer.
+type gzipWriter struct {
+	Writer
+	gz *gzip.Writer
+}
+
+func (w *gzipWriter) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if w.gz != nil {
+		return errors.WithStack(w.gz.Close())
+	}
+
+	return nil
+}
+
+// Export writes the transactions of the iterator until it is exhausted, and returns the number
+// of the written transactions. The transactions are streamed, except the rows of the current
+// row group of the Parquet files. The iterator is not closed.
+func Export(w io.Writer, it *corpbankclient.TransactionIterator, opts *Options) (int, error) {
+	ew, err := NewWriter(w, opts)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	n := 0
+
+	for it.Next() {
+		if err := ew.Write(it.Transaction()); err != nil {
+			return n, errors.WithStack(err)
+		}
+
+		n++
+	}
+
+	if err := it.Err(); err != nil {
+		return n, errors.WithStack(err)
+	}
+
+	return n, errors.WithStack(ew.Close())
+}
+
+// ExportFile writes the transactions of the iterator to the file. If the format of the options is
+// empty, the format and the gzip compression are detected by the extension, e.g. ".csv.gz".
+// The transactions are written to a temporary file in the same directory, which replaces the file
+// only when the export succeeds, so an existing file is kept if the export fails.
+func ExportFile(path string, it *corpbankclient.TransactionIterator, opts *Options) (int, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Format == "" {
+		if err := detectFormat(path, &o); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to create a temporary file for: `%s`", path)
+	}
+
+	// the temporary files are only readable by the owner, unlike the files created by os.Create
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	n, err := writeFile(f, mode, it, &o)
+	if err == nil {
+		err = errors.Wrapf(os.Rename(f.Name(), path), "unable to replace the file: `%s`", path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return n, errors.WithStack(err)
+	}
+
+	return n, nil
+}
+
+// writeFile exports the transactions to the file, and closes it after syncing.
+func writeFile(f *os.File, mode os.FileMode, it *corpbankclient.TransactionIterator, opts *Options) (int, error) {
+	n, err := Export(f, it, opts)
+	if err != nil {
+		f.Close()
+		return n, errors.WithStack(err)
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return n, errors.Wrapf(err, "unable to set the mode of the file: `%s`", f.Name())
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return n, errors.Wrapf(err, "unable to write the file: `%s`", f.Name())
+	}
+
+	return n, errors.Wrapf(f.Close(), "unable to write the file: `%s`", f.Name())
+}
+
+func detectFormat(path string, opts *Options) error {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if ext == ".gz" {
+		opts.Gzip = true
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+
+	switch ext {
+	case ".csv":
+		opts.Format = FormatCSV
+	case ".jsonl", ".ndjson":
+		opts.Format = FormatJSONL
+	case ".parquet":
+		opts.Format = FormatParquet
+	default:
+		return errors.Errorf("unsupported file type: `%s`", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/birapi/go-corpbankclient/corpbanktest"
+)
+
+func TestExportFile(t *testing.T) {
+	s := corpbanktest.NewServer()
+	defer s.Close()
+
+	for _, trx := range testTransactions() {
+		s.AddTransaction(trx)
+	}
+
+	client, err := s.NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+
+	n, err := ExportFile(path, client.TransactionIterator(context.Background()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := len(testTransactions()); n != want {
+		t.Fatalf("ExportFile() = %d, want %d", n, want)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(string(content), "\n"); lines != n+1 {
+		t.Fatalf("%d lines in the file, want %d", lines, n+1)
+	}
+
+	// a failed export keeps the existing file, and leaves no temporary file
+	s.InjectError(corpbanktest.ErrorInjection{StatusCode: 400, Code: "BAD_REQUEST", Times: -1})
+
+	if _, err := ExportFile(path, client.TransactionIterator(context.Background()), nil); err == nil {
+		t.Fatal("ExportFile() = nil, want an error")
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(after) != string(content) {
+		t.Fatal("the existing file is modified by the failed export")
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("%d files in the directory, want 1", len(entries))
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path    string
+		format  Format
+		gzip    bool
+		wantErr bool
+	}{
+		{"a.csv", FormatCSV, false, false},
+		{"a.CSV.gz", FormatCSV, true, false},
+		{"a.jsonl", FormatJSONL, false, false},
+		{"a.ndjson.gz", FormatJSONL, true, false},
+		{"a.parquet", FormatParquet, false, false},
+		{"a.txt", "", false, true},
+		{"a.gz", "", true, true},
+	}
+
+	for _, tt := range tests {
+		opts := Options{}
+
+		err := detectFormat(tt.path, &opts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("detectFormat(%q) = %v, want error %v", tt.path, err, tt.wantErr)
+		}
+
+		if opts.Format != tt.format || opts.Gzip != tt.gzip {
+			t.Errorf("detectFormat(%q) = %s, gzip %v, want %s, gzip %v", tt.path, opts.Format, opts.Gzip, tt.format, tt.gzip)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+)
+
+type jsonlWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+// newJSONLWriter returns a writer of the JSON Lines files, with a flat object per line. The keys are
+// in the order of the columns, the amounts are strings to keep their precision, and the missing
+// values are nulls.
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	jw := &jsonlWriter{w: bufio.NewWriter(w)}
+
+	for _, c := range schema {
+		key, _ := json.Marshal(c.name)
+		jw.keys = append(jw.keys, append(key, ':'))
+	}
+
+	return jw
+}
+
+func (w *jsonlWriter) Write(trx corpbankclient.Transaction) error {
+	buf := []byte{'{'}
+
+	for i, c := range schema {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = append(buf, w.keys[i]...)
+
+		v := c.value(&trx)
+		if v == nil {
+			buf = append(buf, "null"...)
+			continue
+		}
+
+		b, err := json.Marshal(formatText(v))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		buf = append(buf, b...)
+	}
+
+	buf = append(buf, '}', '\n')
+
+	_, err := w.w.Write(buf)
+	return errors.WithStack(err)
+}
+
+func (w *jsonlWriter) Close() error {
+	return errors.WithStack(w.w.Flush())
+}
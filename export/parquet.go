@@ -0,0 +1,458 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const parquetMagic = "PAR1"
+
+const parquetCreatedBy = "go-corpbankclient"
+
+// Enums of the Parquet format.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedDecimal         = 5
+	parquetConvertedTimestampMicros = 10
+
+	parquetLogicalString    = 1
+	parquetLogicalDecimal   = 5
+	parquetLogicalTimestamp = 8
+	parquetTimeUnitMicros   = 2
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecUncompressed = 0
+	parquetCodecGzip         = 2
+
+	parquetDataPage = 0
+)
+
+// parquetDecimalPrecision is the maximum precision of the decimal columns, since their values are
+// variable length byte arrays.
+const parquetDecimalPrecision = 38
+
+const defaultAmountScale = 2
+
+// parquetColumn buffers the values of a column in the current row group.
+type parquetColumn struct {
+	*column
+
+	// defLevels are the definition levels of the optional columns, 0 for the missing values.
+	defLevels []byte
+	values    bytes.Buffer
+}
+
+// parquetChunk is the metadata of a column chunk.
+type parquetChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+type parquetRowGroup struct {
+	chunks   []parquetChunk
+	numRows  int64
+	byteSize int64
+}
+
+// parquetWriter writes the Parquet files, with a data page per column chunk.
+type parquetWriter struct {
+	w            io.Writer
+	offset       int64
+	codec        int32
+	rowGroupSize int
+	amountScale  int32
+
+	columns   []*parquetColumn
+	rows      int
+	rowGroups []parquetRowGroup
+	numRows   int64
+	err       error
+}
+
+func newParquetWriter(w io.Writer, opts *Options) *parquetWriter {
+	pw := &parquetWriter{
+		w:            w,
+		codec:        parquetCodecUncompressed,
+		rowGroupSize: opts.RowGroupSize,
+		amountScale:  defaultAmountScale,
+	}
+
+	if opts.Gzip {
+		pw.codec = parquetCodecGzip
+	}
+
+	if pw.rowGroupSize <= 0 {
+		pw.rowGroupSize = defaultRowGroupSize
+	}
+
+	if opts.AmountScale != nil {
+		pw.amountScale = int32(*opts.AmountScale)
+	}
+
+	for i := range schema {
+		pw.columns = append(pw.columns, &parquetColumn{column: &schema[i]})
+	}
+
+	pw.write([]byte(parquetMagic))
+
+	return pw
+}
+
+func (w *parquetWriter) Write(trx corpbankclient.Transaction) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	// the values are validated first, so that a rejected transaction does not leave a partial row
+	values := make([]interface{}, len(w.columns))
+
+	for i, c := range w.columns {
+		v := c.value(&trx)
+
+		if v == nil && !c.optional {
+			return errors.Errorf("missing value of the required column `%s` in the transaction %s", c.name, trx.ID)
+		}
+
+		if d, ok := v.(decimal.Decimal); ok && !d.Equal(d.Round(w.amountScale)) {
+			return errors.Errorf("the %s of the transaction %s has more than %d decimal places: `%s`", c.name, trx.ID, w.amountScale, d)
+		}
+
+		values[i] = v
+	}
+
+	for i, c := range w.columns {
+		c.add(values[i], w.amountScale)
+	}
+
+	w.rows++
+
+	if w.rows >= w.rowGroupSize {
+		w.flushRowGroup()
+	}
+
+	return w.err
+}
+
+func (w *parquetWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.rows > 0 {
+		w.flushRowGroup()
+	}
+
+	footer := w.footer()
+
+	w.write(footer)
+	w.write(uint32Bytes(uint32(len(footer))))
+	w.write([]byte(parquetMagic))
+
+	return w.err
+}
+
+func (c *parquetColumn) add(v interface{}, scale int32) {
+	if c.optional {
+		if v == nil {
+			c.defLevels = append(c.defLevels, 0)
+			return
+		}
+
+		c.defLevels = append(c.defLevels, 1)
+	}
+
+	switch v := v.(type) {
+	case string:
+		writeByteArray(&c.values, []byte(v))
+	case time.Time:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(v.UnixMicro()))
+		c.values.Write(b[:])
+	case decimal.Decimal:
+		writeByteArray(&c.values, twosComplement(v.Shift(scale).BigInt()))
+	}
+}
+
+// flushRowGroup writes the buffered values as a row group.
+func (w *parquetWriter) flushRowGroup() {
+	rg := parquetRowGroup{numRows: int64(w.rows)}
+
+	for _, c := range w.columns {
+		chunk := w.writeChunk(c, w.rows)
+
+		rg.chunks = append(rg.chunks, chunk)
+		rg.byteSize += chunk.uncompressedSize
+
+		c.defLevels = c.defLevels[:0]
+		c.values.Reset()
+	}
+
+	w.rowGroups = append(w.rowGroups, rg)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+}
+
+// writeChunk writes the values of the column as a data page, with the definition levels of the
+// optional columns before the values.
+func (w *parquetWriter) writeChunk(c *parquetColumn, numValues int) parquetChunk {
+	var page bytes.Buffer
+
+	if c.optional {
+		levels := encodeLevels(c.defLevels)
+		page.Write(uint32Bytes(uint32(len(levels))))
+		page.Write(levels)
+	}
+
+	page.Write(c.values.Bytes())
+
+	data := page.Bytes()
+	uncompressedSize := len(data)
+
+	if w.codec == parquetCodecGzip {
+		var buf bytes.Buffer
+
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(data); err != nil && w.err == nil {
+			w.err = errors.WithStack(err)
+		}
+
+		if err := gz.Close(); err != nil && w.err == nil {
+			w.err = errors.WithStack(err)
+		}
+
+		data = buf.Bytes()
+	}
+
+	t := &thriftWriter{}
+	t.begin()
+	t.i32Field(1, parquetDataPage)
+	t.i32Field(2, int32(uncompressedSize))
+	t.i32Field(3, int32(len(data)))
+	t.structField(5)
+	t.i32Field(1, int32(numValues))
+	t.i32Field(2, parquetEncodingPlain)
+	t.i32Field(3, parquetEncodingRLE)
+	t.i32Field(4, parquetEncodingRLE)
+	t.end()
+	t.end()
+
+	header := t.buf.Bytes()
+
+	chunk := parquetChunk{
+		offset:           w.offset,
+		numValues:        int64(numValues),
+		uncompressedSize: int64(len(header) + uncompressedSize),
+		compressedSize:   int64(len(header) + len(data)),
+	}
+
+	w.write(header)
+	w.write(data)
+
+	return chunk
+}
+
+// footer returns the file metadata.
+func (w *parquetWriter) footer() []byte {
+	t := &thriftWriter{}
+	t.begin()
+
+	t.i32Field(1, 1)
+
+	t.listField(2, thriftStruct, len(w.columns)+1)
+
+	t.begin()
+	t.stringField(4, "schema")
+	t.i32Field(5, int32(len(w.columns)))
+	t.end()
+
+	for _, c := range w.columns {
+		w.writeSchemaElement(t, c.column)
+	}
+
+	t.i64Field(3, w.numRows)
+
+	t.listField(4, thriftStruct, len(w.rowGroups))
+
+	for _, rg := range w.rowGroups {
+		t.begin()
+		t.listField(1, thriftStruct, len(rg.chunks))
+
+		for i, chunk := range rg.chunks {
+			c := w.columns[i]
+
+			t.begin()
+			t.i64Field(2, chunk.offset)
+			t.structField(3)
+
+			t.i32Field(1, physicalType(c.column))
+
+			if c.optional {
+				t.listField(2, thriftI32, 2)
+				t.i32(parquetEncodingPlain)
+				t.i32(parquetEncodingRLE)
+			} else {
+				t.listField(2, thriftI32, 1)
+				t.i32(parquetEncodingPlain)
+			}
+
+			t.listField(3, thriftBinary, 1)
+			t.string(c.name)
+			t.i32Field(4, w.codec)
+			t.i64Field(5, chunk.numValues)
+			t.i64Field(6, chunk.uncompressedSize)
+			t.i64Field(7, chunk.compressedSize)
+			t.i64Field(9, chunk.offset)
+
+			t.end()
+			t.end()
+		}
+
+		t.i64Field(2, rg.byteSize)
+		t.i64Field(3, rg.numRows)
+		t.end()
+	}
+
+	t.stringField(6, parquetCreatedBy)
+	t.end()
+
+	return t.buf.Bytes()
+}
+
+func (w *parquetWriter) writeSchemaElement(t *thriftWriter, c *column) {
+	t.begin()
+	t.i32Field(1, physicalType(c))
+
+	if c.optional {
+		t.i32Field(3, parquetOptional)
+	} else {
+		t.i32Field(3, parquetRequired)
+	}
+
+	t.stringField(4, c.name)
+
+	switch c.typ {
+	case typeString:
+		t.i32Field(6, parquetConvertedUTF8)
+		t.structField(10)
+		t.structField(parquetLogicalString)
+		t.end()
+		t.end()
+
+	case typeTimestamp:
+		t.i32Field(6, parquetConvertedTimestampMicros)
+		t.structField(10)
+		t.structField(parquetLogicalTimestamp)
+		t.boolField(1, true)
+		t.structField(2)
+		t.structField(parquetTimeUnitMicros)
+		t.end()
+		t.end()
+		t.end()
+		t.end()
+
+	case typeDecimal:
+		t.i32Field(6, parquetConvertedDecimal)
+		t.i32Field(7, w.amountScale)
+		t.i32Field(8, parquetDecimalPrecision)
+		t.structField(10)
+		t.structField(parquetLogicalDecimal)
+		t.i32Field(1, w.amountScale)
+		t.i32Field(2, parquetDecimalPrecision)
+		t.end()
+		t.end()
+	}
+
+	t.end()
+}
+
+func physicalType(c *column) int32 {
+	if c.typ == typeTimestamp {
+		return parquetInt64
+	}
+
+	return parquetByteArray
+}
+
+func (w *parquetWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+
+	if err != nil {
+		w.err = errors.WithStack(err)
+	}
+}
+
+func uint32Bytes(v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+
+	return b[:]
+}
+
+func writeByteArray(buf *bytes.Buffer, b []byte) {
+	buf.Write(uint32Bytes(uint32(len(b))))
+	buf.Write(b)
+}
+
+// encodeLevels encodes the definition levels with the RLE encoding of the bit width 1,
+// as the runs of the same levels.
+func encodeLevels(levels []byte) []byte {
+	var buf []byte
+
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		var header [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(header[:], uint64(j-i)<<1)
+
+		buf = append(buf, header[:n]...)
+		buf = append(buf, levels[i])
+
+		i = j
+	}
+
+	return buf
+}
+
+// twosComplement returns the big-endian two's complement representation of the unscaled
+// decimal value, in the minimum number of bytes.
+func twosComplement(x *big.Int) []byte {
+	if x.Sign() >= 0 {
+		b := x.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+
+		return b
+	}
+
+	// -x-1 is not negative, and its bit length determines the number of bytes
+	n := new(big.Int).Not(x).BitLen()/8 + 1
+
+	return new(big.Int).Add(x, new(big.Int).Lsh(big.NewInt(1), uint(8*n))).Bytes()
+}
@@ -0,0 +1,546 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// The reader below decodes the Parquet files independently of the writer, following the format
+// specification: the Thrift compact protocol of the metadata, the RLE/bit-packed hybrid encoding
+// of the definition levels and the plain encoding of the values.
+
+type compactReader struct {
+	r *bytes.Reader
+}
+
+// readStruct decodes a struct as a map of its fields by their IDs. The integers are decoded as int64,
+// the binaries as []byte, the lists as []interface{} and the structs as map[int16]interface{}.
+func (t *compactReader) readStruct() (map[int16]interface{}, error) {
+	fields := map[int16]interface{}{}
+	lastID := int16(0)
+
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == 0 {
+			return fields, nil
+		}
+
+		typ := b & 0x0f
+		id := lastID + int16(b>>4)
+
+		if b>>4 == 0 {
+			v, err := t.readVarint()
+			if err != nil {
+				return nil, err
+			}
+
+			id = int16(unzigzag(v))
+		}
+
+		lastID = id
+
+		switch typ {
+		case 1, 2:
+			fields[id] = typ == 1
+		default:
+			v, err := t.readValue(typ)
+			if err != nil {
+				return nil, err
+			}
+
+			fields[id] = v
+		}
+	}
+}
+
+func (t *compactReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case 1, 2:
+		b, err := t.r.ReadByte()
+		return b == 1, err
+
+	case 3:
+		b, err := t.r.ReadByte()
+		return int64(int8(b)), err
+
+	case 4, 5, 6:
+		v, err := t.readVarint()
+		return unzigzag(v), err
+
+	case 8:
+		n, err := t.readVarint()
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, n)
+		_, err = io.ReadFull(t.r, b)
+
+		return b, err
+
+	case 9:
+		header, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = t.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+
+		list := make([]interface{}, size)
+		for i := range list {
+			if list[i], err = t.readValue(header & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+
+		return list, nil
+
+	case 12:
+		return t.readStruct()
+
+	default:
+		return nil, fmt.Errorf("unsupported compact type: %d", typ)
+	}
+}
+
+func (t *compactReader) readVarint() (uint64, error) {
+	return binary.ReadUvarint(t.r)
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+type parquetFile struct {
+	numRows int64
+	columns []parquetSchemaColumn
+	rows    []map[string]interface{}
+}
+
+type parquetSchemaColumn struct {
+	name     string
+	physical int64
+	optional bool
+	decimal  bool
+	scale    int64
+}
+
+// readParquet decodes the rows of the file, with the strings as string, the timestamps as the
+// microseconds since the epoch, the decimals as decimal.Decimal and the missing values as nil.
+func readParquet(data []byte) (*parquetFile, error) {
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		return nil, fmt.Errorf("missing magic")
+	}
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+
+	meta, err := (&compactReader{bytes.NewReader(data[footerStart : len(data)-8])}).readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("footer: %v", err)
+	}
+
+	f := &parquetFile{numRows: meta[3].(int64)}
+
+	schema := meta[2].([]interface{})
+	if n := schema[0].(map[int16]interface{})[5].(int64); int(n) != len(schema)-1 {
+		return nil, fmt.Errorf("the root has %d children, want %d", n, len(schema)-1)
+	}
+
+	for _, e := range schema[1:] {
+		el := e.(map[int16]interface{})
+
+		c := parquetSchemaColumn{
+			name:     string(el[4].([]byte)),
+			physical: el[1].(int64),
+			optional: el[3].(int64) == 1,
+		}
+
+		// the decimal converted type
+		if converted, ok := el[6]; ok && converted.(int64) == 5 {
+			c.decimal = true
+			c.scale = el[7].(int64)
+		}
+
+		f.columns = append(f.columns, c)
+	}
+
+	for _, rg := range meta[4].([]interface{}) {
+		rowGroup := rg.(map[int16]interface{})
+		numRows := int(rowGroup[3].(int64))
+
+		rows := make([]map[string]interface{}, numRows)
+		for i := range rows {
+			rows[i] = map[string]interface{}{}
+		}
+
+		chunks := rowGroup[1].([]interface{})
+		if len(chunks) != len(f.columns) {
+			return nil, fmt.Errorf("%d column chunks, want %d", len(chunks), len(f.columns))
+		}
+
+		for i, ch := range chunks {
+			values, err := readChunk(data, ch.(map[int16]interface{})[3].(map[int16]interface{}), f.columns[i], numRows)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %v", f.columns[i].name, err)
+			}
+
+			for j, v := range values {
+				rows[j][f.columns[i].name] = v
+			}
+		}
+
+		f.rows = append(f.rows, rows...)
+	}
+
+	return f, nil
+}
+
+func readChunk(data []byte, meta map[int16]interface{}, c parquetSchemaColumn, numRows int) ([]interface{}, error) {
+	if path := meta[3].([]interface{}); len(path) != 1 || string(path[0].([]byte)) != c.name {
+		return nil, fmt.Errorf("path mismatch: %q", path)
+	}
+
+	if meta[1].(int64) != c.physical {
+		return nil, fmt.Errorf("type mismatch: %d", meta[1])
+	}
+
+	r := bytes.NewReader(data[meta[9].(int64):])
+
+	header, err := (&compactReader{r}).readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("page header: %v", err)
+	}
+
+	if header[1].(int64) != 0 {
+		return nil, fmt.Errorf("not a data page: %d", header[1])
+	}
+
+	page := make([]byte, header[3].(int64))
+	if _, err := io.ReadFull(r, page); err != nil {
+		return nil, err
+	}
+
+	switch meta[4].(int64) {
+	case 0:
+	case 2:
+		gz, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			return nil, err
+		}
+
+		if page, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported codec: %d", meta[4])
+	}
+
+	if int64(len(page)) != header[2].(int64) {
+		return nil, fmt.Errorf("uncompressed size %d, want %d", len(page), header[2])
+	}
+
+	if n := header[5].(map[int16]interface{})[1].(int64); int(n) != numRows {
+		return nil, fmt.Errorf("%d values in the page, want %d", n, numRows)
+	}
+
+	present := make([]bool, numRows)
+	for i := range present {
+		present[i] = true
+	}
+
+	if c.optional {
+		n := int(binary.LittleEndian.Uint32(page))
+		if present, err = decodeLevels(page[4:4+n], numRows); err != nil {
+			return nil, err
+		}
+
+		page = page[4+n:]
+	}
+
+	values := make([]interface{}, numRows)
+
+	for i := range values {
+		if !present[i] {
+			continue
+		}
+
+		if c.physical == 2 {
+			values[i] = int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+			continue
+		}
+
+		n := int(binary.LittleEndian.Uint32(page))
+		b := page[4 : 4+n]
+		page = page[4+n:]
+
+		if !c.decimal {
+			values[i] = string(b)
+			continue
+		}
+
+		// big-endian two's complement
+		v := new(big.Int).SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+		}
+
+		values[i] = decimal.NewFromBigInt(v, -int32(c.scale))
+	}
+
+	if len(page) != 0 {
+		return nil, fmt.Errorf("%d trailing bytes in the page", len(page))
+	}
+
+	return values, nil
+}
+
+// decodeLevels decodes the RLE/bit-packed hybrid encoding of the definition levels of bit width 1.
+func decodeLevels(b []byte, n int) ([]bool, error) {
+	r := bytes.NewReader(b)
+
+	var levels []bool
+
+	for len(levels) < n {
+		header, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if header&1 == 0 {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+
+			for i := uint64(0); i < header>>1; i++ {
+				levels = append(levels, v == 1)
+			}
+
+			continue
+		}
+
+		// the bit-packed groups of 8 levels, a byte per group for the bit width 1
+		for i := uint64(0); i < header>>1; i++ {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+
+			for j := 0; j < 8; j++ {
+				levels = append(levels, v>>j&1 == 1)
+			}
+		}
+	}
+
+	return levels[:n], nil
+}
+
+func testTransactions() []corpbankclient.Transaction {
+	date := time.Date(2024, time.March, 1, 9, 30, 15, 123456000, time.UTC)
+	paymentID := uuid.MustParse("7d4d7a51-0d0a-4f7a-9a49-6b0e1d3d2c11")
+
+	trxs := []corpbankclient.Transaction{
+		{
+			Amount:         decimal.RequireFromString("1234.50"),
+			Direction:      corpbankclient.TrxDirectionIncoming,
+			TransferMethod: corpbankclient.TrxTransferMethodFAST,
+			RefCode:        "REF-1",
+			Description:    "Kira ödemesi",
+			Sender: &corpbankclient.TransactionParticipant{
+				BankCode:       "00062",
+				IBAN:           "TR400006200000000000000001",
+				IdentityNumber: "10000000146",
+				Name:           "Ayşe Yılmaz",
+			},
+			ReceivedAt: date.Add(time.Second),
+		},
+		{
+			Amount:         decimal.RequireFromString("-99999999999.99"),
+			Direction:      corpbankclient.TrxDirectionOutgoing,
+			TransferMethod: corpbankclient.TrxTransferMethodEFT,
+			Recipient: &corpbankclient.TransactionParticipant{
+				IBAN: "TR420001000000000000000001",
+				Name: "ACME A.Ş.",
+			},
+			PaymentID: &paymentID,
+		},
+		{Amount: decimal.RequireFromString("0.01"), Direction: corpbankclient.TrxDirectionIncoming},
+		{Amount: decimal.RequireFromString("-0.01"), Direction: corpbankclient.TrxDirectionOutgoing},
+		{Amount: decimal.RequireFromString("128"), Direction: corpbankclient.TrxDirectionIncoming},
+	}
+
+	for i := range trxs {
+		trxs[i].ID = uuid.NewSHA1(uuid.Nil, []byte{byte(i)})
+		trxs[i].Date = date.Add(time.Duration(i) * time.Hour)
+		trxs[i].Account = corpbankclient.TransactionAccount{BankCode: "00061", IBAN: "TR330006100519786457841326"}
+		trxs[i].Currency = "TRY"
+	}
+
+	return trxs
+}
+
+func expectedRow(trx corpbankclient.Transaction) map[string]interface{} {
+	row := map[string]interface{}{
+		"id":                        trx.ID.String(),
+		"date":                      trx.Date.UnixNano() / 1000,
+		"received_at":               nil,
+		"account_bank_code":         trx.Account.BankCode,
+		"account_iban":              trx.Account.IBAN,
+		"amount":                    trx.Amount,
+		"currency":                  trx.Currency,
+		"direction":                 string(trx.Direction),
+		"transfer_method":           string(trx.TransferMethod),
+		"reference_code":            trx.RefCode,
+		"description":               trx.Description,
+		"sender_bank_code":          nil,
+		"sender_iban":               nil,
+		"sender_identity_number":    nil,
+		"sender_name":               nil,
+		"recipient_bank_code":       nil,
+		"recipient_iban":            nil,
+		"recipient_identity_number": nil,
+		"recipient_name":            nil,
+		"payment_id":                nil,
+	}
+
+	if !trx.ReceivedAt.IsZero() {
+		row["received_at"] = trx.ReceivedAt.UnixNano() / 1000
+	}
+
+	for prefix, p := range map[string]*corpbankclient.TransactionParticipant{"sender": trx.Sender, "recipient": trx.Recipient} {
+		if p != nil {
+			row[prefix+"_bank_code"] = p.BankCode
+			row[prefix+"_iban"] = p.IBAN
+			row[prefix+"_identity_number"] = p.IdentityNumber
+			row[prefix+"_name"] = p.Name
+		}
+	}
+
+	if trx.PaymentID != nil {
+		row["payment_id"] = trx.PaymentID.String()
+	}
+
+	return row
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	zero := 0
+	four := 4
+
+	tests := []struct {
+		name  string
+		opts  Options
+		trxs  []corpbankclient.Transaction
+		scale int64
+	}{
+		{"default", Options{}, testTransactions(), 2},
+		{"gzip", Options{Gzip: true}, testTransactions(), 2},
+		{"row groups", Options{RowGroupSize: 2}, testTransactions(), 2},
+		{"gzip row groups", Options{Gzip: true, RowGroupSize: 3}, testTransactions(), 2},
+		{"scale 4", Options{AmountScale: &four}, testTransactions(), 4},
+		{"scale 0", Options{AmountScale: &zero}, testTransactions()[4:], 0},
+		{"empty", Options{}, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Format = FormatParquet
+
+			var buf bytes.Buffer
+
+			w, err := NewWriter(&buf, &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, trx := range tt.trxs {
+				if err := w.Write(trx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := readParquet(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if f.numRows != int64(len(tt.trxs)) || len(f.rows) != len(tt.trxs) {
+				t.Fatalf("read %d rows (%d in the metadata), want %d", len(f.rows), f.numRows, len(tt.trxs))
+			}
+
+			names := Columns()
+			for i, c := range f.columns {
+				if c.name != names[i] {
+					t.Errorf("column #%d = %s, want %s", i, c.name, names[i])
+				}
+
+				if c.name == "amount" && c.scale != tt.scale {
+					t.Errorf("amount scale = %d, want %d", c.scale, tt.scale)
+				}
+			}
+
+			for i, trx := range tt.trxs {
+				for name, want := range expectedRow(trx) {
+					got := f.rows[i][name]
+
+					if d, ok := want.(decimal.Decimal); ok {
+						if g, ok := got.(decimal.Decimal); !ok || !g.Equal(d) {
+							t.Errorf("row %d: %s = %v, want %s", i, name, got, d)
+						}
+
+						continue
+					}
+
+					if got != want {
+						t.Errorf("row %d: %s = %#v, want %#v", i, name, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParquetRejectsExcessScale(t *testing.T) {
+	w, err := NewWriter(io.Discard, &Options{Format: FormatParquet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trx := testTransactions()[0]
+	trx.Amount = decimal.RequireFromString("1.005")
+
+	if err := w.Write(trx); err == nil {
+		t.Fatal("Write() = nil, want an error for 3 decimal places")
+	}
+
+	negative := -1
+	if _, err := NewWriter(io.Discard, &Options{Format: FormatParquet, AmountScale: &negative}); err == nil {
+		t.Fatal("NewWriter() = nil, want an error for a negative scale")
+	}
+}
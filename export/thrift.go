@@ -0,0 +1,113 @@
+package export
+
+import "bytes"
+
+// Type IDs of the Thrift compact protocol.
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftStruct    = 12
+)
+
+// thriftWriter encodes the structs in the Thrift compact protocol, which is used by the metadata
+// of the Parquet files. The fields are written by their IDs, and the fields of the nested structs
+// are written between their begin and end calls.
+type thriftWriter struct {
+	buf bytes.Buffer
+
+	// lastIDs are the last field IDs of the nested structs, since the field headers are delta encoded.
+	lastIDs []int16
+}
+
+// begin begins a top-level struct, or a struct element of a list.
+func (t *thriftWriter) begin() {
+	t.lastIDs = append(t.lastIDs, 0)
+}
+
+// end ends the struct, with a stop field.
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) stringField(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.string(s)
+}
+
+func (t *thriftWriter) boolField(id int16, v bool) {
+	if v {
+		t.fieldHeader(id, thriftBoolTrue)
+	} else {
+		t.fieldHeader(id, thriftBoolFalse)
+	}
+}
+
+// listField writes the header of a list, the elements are written after it.
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+
+	t.buf.WriteByte(0xf0 | elemType)
+	t.varint(uint64(size))
+}
+
+// i32 writes an i32 element of a list.
+func (t *thriftWriter) i32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+// string writes a binary element of a list.
+func (t *thriftWriter) string(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastIDs[len(t.lastIDs)-1]
+
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+
+	*last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	for v >= 0x80 {
+		t.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+
+	t.buf.WriteByte(byte(v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,66 @@
+package iban
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// banks.csv is the list of the Turkish banks by their 5-digit EFT codes.
+//
+//go:embed banks.csv
+var banksCSV string
+
+var bankNames = parseBanks(banksCSV)
+
+// BankCode returns the 5-digit bank code of the Turkish IBAN.
+func BankCode(iban string) (string, error) {
+	if err := Validate(iban); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !strings.HasPrefix(iban, "TR") {
+		return "", errors.Errorf("not a Turkish IBAN: `%s`", iban)
+	}
+
+	return iban[4:9], nil
+}
+
+// BankName returns the name of the bank by its 5-digit code.
+func BankName(bankCode string) (string, bool) {
+	name, ok := bankNames[bankCode]
+	return name, ok
+}
+
+// BankNameOf returns the name of the bank of the Turkish IBAN.
+func BankNameOf(iban string) (string, bool) {
+	bankCode, err := BankCode(iban)
+	if err != nil {
+		return "", false
+	}
+
+	return BankName(bankCode)
+}
+
+func parseBanks(content string) map[string]string {
+	banks := map[string]string{}
+
+	s := bufio.NewScanner(strings.NewReader(content))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, ",")
+		if i < 0 {
+			continue
+		}
+
+		banks[line[:i]] = line[i+1:]
+	}
+
+	return banks
+}
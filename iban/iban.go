@@ -0,0 +1,104 @@
+// Package iban validates the International Bank Account Numbers (ISO 13616)
+// and resolves the banks of the Turkish IBANs.
+package iban
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidIBAN is returned when the IBAN is malformed or its checksum does not match.
+var ErrInvalidIBAN = errors.New("invalid IBAN")
+
+// lengths are the IBAN lengths of the countries in the IBAN registry.
+var lengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22,
+	"BH": 22, "BI": 27, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24,
+	"DE": 22, "DJ": 27, "DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18,
+	"FK": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27,
+	"GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27,
+	"JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20,
+	"LV": 21, "LY": 25, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27,
+	"MT": 31, "MU": 30, "NI": 28, "NL": 18, "NO": 15, "OM": 23, "PK": 24, "PL": 28,
+	"PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33, "SA": 24, "SC": 31,
+	"SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "SO": 23, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// Normalize removes the spaces of the printed format and converts the IBAN to upper case,
+// e.g. "tr33 0006 1005 1978 6457 8413 26" becomes "TR330006100519786457841326".
+func Normalize(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// Validate checks the country code, the length and the checksum of the IBAN, in the electronic
+// format. Normalize should be called first to accept the printed format.
+func Validate(iban string) error {
+	if len(iban) < 4 {
+		return errors.Wrapf(ErrInvalidIBAN, "too short: `%s`", iban)
+	}
+
+	country := iban[:2]
+
+	length, ok := lengths[country]
+	if !ok {
+		return errors.Wrapf(ErrInvalidIBAN, "unknown country code: `%s`", iban)
+	}
+
+	if len(iban) != length {
+		return errors.Wrapf(ErrInvalidIBAN, "the length must be %d for %s: `%s`", length, country, iban)
+	}
+
+	for i := 0; i < len(iban); i++ {
+		if !isDigit(iban[i]) && !isUpper(iban[i]) {
+			return errors.Wrapf(ErrInvalidIBAN, "invalid character at position %d: `%s`", i+1, iban)
+		}
+	}
+
+	if !isDigit(iban[2]) || !isDigit(iban[3]) {
+		return errors.Wrapf(ErrInvalidIBAN, "the check digits must be numeric: `%s`", iban)
+	}
+
+	// the reserved digit of the Turkish IBANs, following the bank code
+	if country == "TR" && iban[9] != '0' {
+		return errors.Wrapf(ErrInvalidIBAN, "the reserved digit must be zero: `%s`", iban)
+	}
+
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return errors.Wrapf(ErrInvalidIBAN, "checksum mismatch: `%s`", iban)
+	}
+
+	return nil
+}
+
+// IsValid reports whether the IBAN is valid, in the electronic format.
+func IsValid(iban string) bool {
+	return Validate(iban) == nil
+}
+
+// mod97 computes the remainder of the number, where the letters are replaced by
+// two digits (A = 10, B = 11, ..., Z = 35), divided by 97.
+func mod97(s string) int {
+	r := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if isDigit(c) {
+			r = (r*10 + int(c-'0')) % 97
+		} else {
+			r = (r*100 + int(c-'A') + 10) % 97
+		}
+	}
+
+	return r
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,120 @@
+package iban
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"TR330006100519786457841326", "TR330006100519786457841326"},
+		{"tr33 0006 1005 1978 6457 8413 26", "TR330006100519786457841326"},
+		{" GB82 WEST 1234 5698 7654 32\t", "GB82WEST12345698765432"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		iban  string
+		valid bool
+	}{
+		{"turkish", "TR330006100519786457841326", true},
+		{"british", "GB82WEST12345698765432", true},
+		{"german", "DE89370400440532013000", true},
+		{"norwegian", "NO9386011117947", true},
+		{"empty", "", false},
+		{"too short", "TR3", false},
+		{"unknown country", "XX330006100519786457841326", false},
+		{"too long", "TR3300061005197864578413260", false},
+		{"too short for the country", "TR33000610051978645784132", false},
+		{"lower case", "tr330006100519786457841326", false},
+		{"printed format", "TR33 0006 1005 1978 6457 8413 26", false},
+		{"non-numeric check digits", "GBAAWEST12345698765432", false},
+		{"non-zero reserved digit", "TR150006210000000000000001", false},
+		{"checksum mismatch", "TR340006100519786457841326", false},
+		{"transposed digits", "GB82WEST12345698765423", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.iban)
+
+			if tt.valid && err != nil {
+				t.Fatalf("Validate(%q) = %v, want nil", tt.iban, err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidIBAN) {
+				t.Fatalf("Validate(%q) = %v, want ErrInvalidIBAN", tt.iban, err)
+			}
+
+			if got := IsValid(tt.iban); got != tt.valid {
+				t.Fatalf("IsValid(%q) = %v, want %v", tt.iban, got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestBankCode(t *testing.T) {
+	tests := []struct {
+		iban     string
+		want     string
+		wantErr  bool
+		bankName string
+	}{
+		{"TR330006100519786457841326", "00061", false, ""},
+		{"TR400006200000000000000001", "00062", false, "Türkiye Garanti Bankası"},
+		{"TR420001000000000000000001", "00010", false, "Türkiye Cumhuriyeti Ziraat Bankası"},
+		{"TR450099900000000000000001", "00999", false, ""},
+		{"GB82WEST12345698765432", "", true, ""},
+		{"TR340006100519786457841326", "", true, ""},
+	}
+
+	for _, tt := range tests {
+		got, err := BankCode(tt.iban)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("BankCode(%q) error = %v, want error %v", tt.iban, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("BankCode(%q) = %q, want %q", tt.iban, got, tt.want)
+		}
+
+		name, ok := BankNameOf(tt.iban)
+		if ok != (tt.bankName != "") || name != tt.bankName {
+			t.Errorf("BankNameOf(%q) = %q, %v, want %q", tt.iban, name, ok, tt.bankName)
+		}
+	}
+}
+
+func TestParseBanks(t *testing.T) {
+	banks := parseBanks("# code,name\n\n00010,Ziraat\ninvalid line\n00046,Akbank, A.Ş.\n")
+
+	want := map[string]string{
+		"00010": "Ziraat",
+		"00046": "Akbank, A.Ş.",
+	}
+
+	if len(banks) != len(want) {
+		t.Fatalf("parseBanks() = %v, want %v", banks, want)
+	}
+
+	for code, name := range want {
+		if banks[code] != name {
+			t.Errorf("parseBanks()[%q] = %q, want %q", code, banks[code], name)
+		}
+	}
+}
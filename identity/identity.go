@@ -0,0 +1,125 @@
+// Package identity validates the Turkish identification numbers: the national ID number (TCKN),
+// the foreign ID number (YKN) and the tax number (VKN).
+package identity
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidID is returned when the identification number is malformed or its checksum does not match.
+var ErrInvalidID = errors.New("invalid identification number")
+
+const (
+	tcknLen = 11
+	vknLen  = 10
+
+	// yknPrefix is the prefix of the foreign ID numbers, which are issued to the foreign residents.
+	yknPrefix = "99"
+
+	minPassportLen = 5
+	maxPassportLen = 20
+)
+
+// ValidateTCKN validates the national ID number (T.C. Kimlik No) of the Turkish citizens.
+func ValidateTCKN(tckn string) error {
+	d, err := digits(tckn, tcknLen)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if d[0] == 0 {
+		return errors.Wrapf(ErrInvalidID, "TCKN can not start with zero: `%s`", tckn)
+	}
+
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return errors.Wrapf(ErrInvalidID, "TCKN checksum mismatch: `%s`", tckn)
+	}
+
+	sum := 0
+	for _, v := range d[:10] {
+		sum += v
+	}
+
+	if sum%10 != d[10] {
+		return errors.Wrapf(ErrInvalidID, "TCKN checksum mismatch: `%s`", tckn)
+	}
+
+	return nil
+}
+
+// ValidateYKN validates the foreign ID number (Yabancı Kimlik No), which follows the TCKN algorithm
+// and starts with 99.
+func ValidateYKN(ykn string) error {
+	if !strings.HasPrefix(ykn, yknPrefix) {
+		return errors.Wrapf(ErrInvalidID, "YKN must start with %s: `%s`", yknPrefix, ykn)
+	}
+
+	return errors.WithStack(ValidateTCKN(ykn))
+}
+
+// ValidateVKN validates the tax number (Vergi Kimlik No) of the companies.
+func ValidateVKN(vkn string) error {
+	d, err := digits(vkn, vknLen)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sum := 0
+
+	for i := 0; i < 9; i++ {
+		tmp := (d[i] + 9 - i) % 10
+		v := (tmp << (9 - i)) % 9
+
+		if tmp != 0 && v == 0 {
+			v = 9
+		}
+
+		sum += v
+	}
+
+	if (10-sum%10)%10 != d[9] {
+		return errors.Wrapf(ErrInvalidID, "VKN checksum mismatch: `%s`", vkn)
+	}
+
+	return nil
+}
+
+// ValidatePassport checks the format of the passport number, since it has no checksum:
+// 5 to 20 upper case letters or digits.
+func ValidatePassport(passport string) error {
+	if len(passport) < minPassportLen || len(passport) > maxPassportLen {
+		return errors.Wrapf(ErrInvalidID, "the passport number must be %d to %d characters: `%s`", minPassportLen, maxPassportLen, passport)
+	}
+
+	for i := 0; i < len(passport); i++ {
+		if c := passport[i]; (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return errors.Wrapf(ErrInvalidID, "invalid character in the passport number at position %d: `%s`", i+1, passport)
+		}
+	}
+
+	return nil
+}
+
+// digits parses the number, which must be exactly n digits.
+func digits(s string, n int) ([]int, error) {
+	if len(s) != n {
+		return nil, errors.Wrapf(ErrInvalidID, "must be %d digits: `%s`", n, s)
+	}
+
+	d := make([]int, n)
+
+	for i := 0; i < n; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return nil, errors.Wrapf(ErrInvalidID, "must be %d digits: `%s`", n, s)
+		}
+
+		d[i] = int(s[i] - '0')
+	}
+
+	return d, nil
+}
@@ -0,0 +1,59 @@
+package identity
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		id       string
+		valid    bool
+	}{
+		{"TCKN", ValidateTCKN, "10000000146", true},
+		{"TCKN", ValidateTCKN, "12345678950", true},
+		{"TCKN", ValidateTCKN, "99123456740", true},
+		{"TCKN starting with zero", ValidateTCKN, "02345678950", false},
+		{"TCKN with a wrong 10th digit", ValidateTCKN, "12345678960", false},
+		{"TCKN with a wrong 11th digit", ValidateTCKN, "12345678951", false},
+		{"TCKN too short", ValidateTCKN, "1234567895", false},
+		{"TCKN too long", ValidateTCKN, "123456789500", false},
+		{"TCKN with a letter", ValidateTCKN, "1234567895A", false},
+		{"TCKN empty", ValidateTCKN, "", false},
+
+		{"YKN", ValidateYKN, "99123456740", true},
+		{"YKN without the prefix", ValidateYKN, "12345678950", false},
+		{"YKN checksum mismatch", ValidateYKN, "99123456741", false},
+
+		{"VKN", ValidateVKN, "1234567890", true},
+		{"VKN", ValidateVKN, "9876543217", true},
+		{"VKN", ValidateVKN, "0000000001", true},
+		{"VKN checksum mismatch", ValidateVKN, "1234567891", false},
+		{"VKN too long", ValidateVKN, "12345678950", false},
+		{"VKN with a space", ValidateVKN, "123456789 ", false},
+
+		{"passport", ValidatePassport, "U12345678", true},
+		{"passport", ValidatePassport, "AB123", true},
+		{"passport too short", ValidatePassport, "AB12", false},
+		{"passport too long", ValidatePassport, "A12345678901234567890", false},
+		{"passport with lower case letters", ValidatePassport, "u12345678", false},
+		{"passport with a dash", ValidatePassport, "U1234-5678", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate(tt.id)
+
+			if tt.valid && err != nil {
+				t.Fatalf("validate(%q) = %v, want nil", tt.id, err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidID) {
+				t.Fatalf("validate(%q) = %v, want ErrInvalidID", tt.id, err)
+			}
+		})
+	}
+}
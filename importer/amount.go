@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// currencySymbols are stripped from the amounts.
+var currencySymbols = []string{"TRY", "TL", "₺"}
+
+// ParseAmount parses an amount in the Turkish format, where the dots separate the thousands
+// and the comma is the decimal separator, e.g. "1.234,56" or "1234,5 TL".
+func ParseAmount(s string) (decimal.Decimal, error) {
+	v := strings.TrimSpace(s)
+
+	for _, sym := range currencySymbols {
+		v = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(v, sym), sym))
+	}
+
+	v = strings.Join(strings.Fields(v), "")
+
+	intPart, fracPart := v, ""
+	if i := strings.LastIndex(v, ","); i >= 0 {
+		intPart, fracPart = v[:i], v[i+1:]
+
+		if fracPart == "" || strings.ContainsAny(fracPart, ".,") {
+			return decimal.Decimal{}, errors.Errorf("invalid amount: `%s`", s)
+		}
+	}
+
+	// the thousands separators must group the digits by three
+	if groups := strings.Split(intPart, "."); len(groups) > 1 {
+		for i, g := range groups {
+			if (i == 0 && (len(g) == 0 || len(g) > 3)) || (i > 0 && len(g) != 3) {
+				return decimal.Decimal{}, errors.Errorf("invalid thousands separators: `%s`", s)
+			}
+		}
+
+		intPart = strings.Join(groups, "")
+	}
+
+	if fracPart != "" {
+		intPart += "." + fracPart
+	}
+
+	d, err := decimal.NewFromString(intPart)
+	if err != nil {
+		return decimal.Decimal{}, errors.Errorf("invalid amount: `%s`", s)
+	}
+
+	return d, nil
+}
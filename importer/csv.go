@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// utf8BOM is written by Excel at the beginning of the UTF-8 CSV files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ReadCSV imports the CSV file. The first row is the header.
+func ReadCSV(r io.Reader, opts *Options) (*Report, error) {
+	br := bufio.NewReader(r)
+
+	if bom, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	var comma rune
+	if opts != nil && opts.Comma != 0 {
+		comma = opts.Comma
+	} else {
+		comma = detectComma(br)
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+
+	next := func() (*row, error) {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, errors.Wrap(err, "unable to read the CSV file")
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		r := &row{line: line, cells: make([]cell, len(record))}
+		for i, v := range record {
+			r.cells[i] = cell{value: v}
+		}
+
+		return r, nil
+	}
+
+	return importRows(next, opts)
+}
+
+// detectComma returns the first delimiter found in the header line, since the CSV files
+// exported in the Turkish locale are delimited by semicolons.
+func detectComma(br *bufio.Reader) rune {
+	// the header line is expected within the buffer of the reader
+	header, _ := br.Peek(br.Size())
+
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+
+	if i := bytes.IndexAny(header, ";,\t"); i >= 0 {
+		return rune(header[i])
+	}
+
+	return ','
+}
@@ -0,0 +1,406 @@
+// Package importer reads the bulk payment lists from CSV and XLSX files into payment orders,
+// and reports the invalid rows by their line numbers before anything is sent.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/birapi/go-corpbankclient/iban"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Field is a field of the payment order, which is read from a column.
+type Field string
+
+const (
+	FieldSenderIBAN      Field = "senderIBAN"
+	FieldRecipientIBAN   Field = "recipientIBAN"
+	FieldRecipientName   Field = "recipientName"
+	FieldRecipientID     Field = "recipientID"
+	FieldRecipientIDType Field = "recipientIDType"
+	FieldAmount          Field = "amount"
+	FieldRefCode         Field = "refCode"
+	FieldDescription     Field = "description"
+)
+
+// Mapping maps the fields to their accepted column headers, which are matched case-insensitively.
+type Mapping map[Field][]string
+
+// DefaultMapping accepts the common English and Turkish column headers.
+var DefaultMapping = Mapping{
+	FieldSenderIBAN:      {"Sender IBAN", "Gönderen IBAN"},
+	FieldRecipientIBAN:   {"IBAN", "Recipient IBAN", "Alıcı IBAN"},
+	FieldRecipientName:   {"Name", "Recipient Name", "Ad Soyad", "Alıcı Adı", "Unvan"},
+	FieldRecipientID:     {"TCKN", "VKN", "Identity Number", "TC Kimlik No", "Kimlik No", "Vergi No"},
+	FieldRecipientIDType: {"Identity Type", "Kimlik Tipi"},
+	FieldAmount:          {"Amount", "Tutar"},
+	FieldRefCode:         {"Reference", "RefCode", "Referans"},
+	FieldDescription:     {"Description", "Açıklama"},
+}
+
+// maxNumericPlaces drops the floating point noise of the numeric cells.
+const maxNumericPlaces = 8
+
+// requiredFields must be mapped to a column of the file.
+var requiredFields = []Field{FieldRecipientIBAN, FieldRecipientName, FieldRecipientID, FieldAmount}
+
+// Options customizes the import.
+type Options struct {
+	// Mapping maps the fields to the column headers. Defaults to DefaultMapping.
+	Mapping Mapping
+
+	// SenderIBAN is the sender IBAN of the rows without a sender IBAN column.
+	SenderIBAN string
+
+	// Comma is the field delimiter of the CSV files. Defaults to the first of `;`, `,` or tab
+	// found in the header line.
+	Comma rune
+
+	// DotDecimal parses the text amounts with a decimal point (e.g. "1234.56"), instead of
+	// the Turkish format (e.g. "1.234,56"). The numeric cells of the XLSX files are not affected.
+	DotDecimal bool
+
+	// Sheet is the name of the XLSX sheet to read. Defaults to the first sheet.
+	Sheet string
+}
+
+// Issue is a validation error of a row.
+type Issue struct {
+	Line    int
+	Field   Field
+	Value   string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s: %s: `%s`", i.Line, i.Field, i.Message, i.Value)
+}
+
+// Report is the result of an import.
+type Report struct {
+	// Orders are the payment orders of the valid rows.
+	Orders []corpbankclient.PaymentOrder
+
+	// Lines are the line numbers of the orders, in the same order.
+	Lines []int
+
+	// Issues are the validation errors of the invalid rows, which are excluded from the orders.
+	Issues []Issue
+}
+
+// OK reports whether all the rows are valid.
+func (r *Report) OK() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d valid rows, %d issues", len(r.Orders), len(r.Issues))
+
+	for _, i := range r.Issues {
+		fmt.Fprintf(&b, "\n%s", i)
+	}
+
+	return b.String()
+}
+
+// cell is a cell value with its type, since the numeric cells of the XLSX files are not localized.
+type cell struct {
+	value   string
+	numeric bool
+}
+
+type row struct {
+	line  int
+	cells []cell
+}
+
+// ReadFile imports the CSV or XLSX file, by its extension.
+func ReadFile(path string, opts *Options) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the file: `%s`", path)
+	}
+
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return ReadXLSX(f, fi.Size(), opts)
+
+	case ".csv", ".txt":
+		return ReadCSV(f, opts)
+
+	default:
+		return nil, errors.Errorf("unsupported file type: `%s`", path)
+	}
+}
+
+// importRows maps the rows onto the payment orders, the first row is the header.
+func importRows(next func() (*row, error), opts *Options) (*Report, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	mapping := opts.Mapping
+	if mapping == nil {
+		mapping = DefaultMapping
+	}
+
+	header, err := next()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	columns, err := mapColumns(header, mapping, opts.SenderIBAN == "")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	report := &Report{}
+
+	for {
+		r, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if isEmpty(r) {
+			continue
+		}
+
+		o, issues := parseRow(r, columns, opts)
+		if len(issues) > 0 {
+			report.Issues = append(report.Issues, issues...)
+			continue
+		}
+
+		report.Orders = append(report.Orders, *o)
+		report.Lines = append(report.Lines, r.line)
+	}
+
+	return report, nil
+}
+
+// mapColumns returns the column indexes of the fields.
+func mapColumns(header *row, mapping Mapping, senderRequired bool) (map[Field]int, error) {
+	columns := map[Field]int{}
+
+	for i, c := range header.cells {
+		h := foldHeader(c.value)
+
+		for field, names := range mapping {
+			for _, name := range names {
+				if _, ok := columns[field]; !ok && h == foldHeader(name) {
+					columns[field] = i
+				}
+			}
+		}
+	}
+
+	required := requiredFields
+	if senderRequired {
+		required = append([]Field{FieldSenderIBAN}, required...)
+	}
+
+	var missing []string
+
+	for _, f := range required {
+		if _, ok := columns[f]; !ok {
+			missing = append(missing, string(f))
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.Errorf("missing columns at line %d: %s", header.line, strings.Join(missing, ", "))
+	}
+
+	return columns, nil
+}
+
+func parseRow(r *row, columns map[Field]int, opts *Options) (*corpbankclient.PaymentOrder, []Issue) {
+	var issues []Issue
+
+	get := func(f Field) cell {
+		if i, ok := columns[f]; ok && i < len(r.cells) {
+			return cell{value: strings.TrimSpace(r.cells[i].value), numeric: r.cells[i].numeric}
+		}
+
+		return cell{}
+	}
+
+	issue := func(f Field, value, msg string) {
+		issues = append(issues, Issue{Line: r.line, Field: f, Value: value, Message: msg})
+	}
+
+	var err error
+
+	o := &corpbankclient.PaymentOrder{
+		RecipientName: get(FieldRecipientName).value,
+		RefCode:       get(FieldRefCode).value,
+		Description:   get(FieldDescription).value,
+	}
+
+	o.SenderIBAN = opts.SenderIBAN
+	if c := get(FieldSenderIBAN); c.value != "" {
+		o.SenderIBAN = c.value
+
+		if err := checkNumericText(c); err != nil {
+			issue(FieldSenderIBAN, c.value, errorMessage(err))
+		}
+	}
+
+	o.SenderIBAN = iban.Normalize(o.SenderIBAN)
+	if err := iban.Validate(o.SenderIBAN); err != nil {
+		issue(FieldSenderIBAN, o.SenderIBAN, errorMessage(err))
+	}
+
+	recipientIBAN := get(FieldRecipientIBAN)
+	if err := checkNumericText(recipientIBAN); err != nil {
+		issue(FieldRecipientIBAN, recipientIBAN.value, errorMessage(err))
+	}
+
+	o.RecipientIBAN = iban.Normalize(recipientIBAN.value)
+	if err := iban.Validate(o.RecipientIBAN); err != nil {
+		issue(FieldRecipientIBAN, o.RecipientIBAN, errorMessage(err))
+	}
+
+	if o.RecipientName == "" {
+		issue(FieldRecipientName, "", "missing recipient name")
+	}
+
+	o.RecipientIDType = corpbankclient.RecipientIDType(strings.ToUpper(get(FieldRecipientIDType).value))
+
+	recipientID := get(FieldRecipientID)
+
+	o.RecipientIdentityNum, err = identityNumber(recipientID)
+	if err != nil {
+		issue(FieldRecipientID, recipientID.value, errorMessage(err))
+	} else {
+		// the payroll lists mix the national ID numbers of the employees and the tax numbers of
+		// the suppliers, so the type is detected if there is no type column
+		if o.RecipientIDType == "" {
+			o.RecipientIDType, err = corpbankclient.DetectRecipientIDType(o.RecipientIdentityNum)
+		} else {
+			err = corpbankclient.ValidateRecipientID(o.RecipientIDType, o.RecipientIdentityNum)
+		}
+
+		if err != nil {
+			issue(FieldRecipientID, o.RecipientIdentityNum, errorMessage(err))
+		}
+	}
+
+	amount := get(FieldAmount)
+
+	if amount.numeric {
+		// the numeric cells are floating point numbers, e.g. "1234.5599999999999"
+		o.TransferAmount, err = decimal.NewFromString(amount.value)
+		o.TransferAmount = o.TransferAmount.Round(maxNumericPlaces)
+	} else if opts.DotDecimal {
+		o.TransferAmount, err = decimal.NewFromString(amount.value)
+	} else {
+		o.TransferAmount, err = ParseAmount(amount.value)
+	}
+
+	if err != nil {
+		issue(FieldAmount, amount.value, "invalid amount")
+	} else if !o.TransferAmount.IsPositive() {
+		issue(FieldAmount, amount.value, "the amount must be positive")
+	} else if !o.TransferAmount.Equal(o.TransferAmount.Round(2)) {
+		issue(FieldAmount, amount.value, "the amount can not have more than 2 decimal places")
+	}
+
+	return o, issues
+}
+
+// vknLength is the length of the tax numbers, which may start with zeros.
+const vknLength = 10
+
+// checkNumericText rejects the numeric cells of the text columns which are written in the
+// exponent form, e.g. "1.2345678901E10", since their digits may be lost.
+func checkNumericText(c cell) error {
+	if c.numeric && strings.ContainsAny(c.value, "Ee") {
+		return errors.Errorf("the number is in the exponent form, format the column as text: `%s`", c.value)
+	}
+
+	return nil
+}
+
+// identityNumber reads the identity number as text. The numeric cells lose the leading zeros
+// of the tax numbers, so they are padded to 10 digits.
+func identityNumber(c cell) (string, error) {
+	if err := checkNumericText(c); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !c.numeric {
+		return c.value, nil
+	}
+
+	id := strings.TrimSuffix(c.value, ".0")
+
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return "", errors.Errorf("the number is not an integer, format the column as text: `%s`", c.value)
+		}
+	}
+
+	if id != "" && len(id) < vknLength {
+		id = strings.Repeat("0", vknLength-len(id)) + id
+	}
+
+	return id, nil
+}
+
+// errorMessage strips the value from the validation error, since it is reported separately.
+func errorMessage(err error) string {
+	msg := err.Error()
+
+	if i := strings.Index(msg, ": `"); i >= 0 {
+		return msg[:i]
+	}
+
+	return msg
+}
+
+// foldHeader folds the header for the comparison, ignoring the differences of the dotted and
+// dotless i letters, which are commonly mixed in the Turkish headers (e.g. "IBAN" and "İBAN").
+func foldHeader(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case 'I', 'İ', 'ı':
+			return 'i'
+		}
+
+		return unicode.ToLower(r)
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func isEmpty(r *row) bool {
+	for _, c := range r.cells {
+		if strings.TrimSpace(c.value) != "" {
+			return false
+		}
+	}
+
+	return true
+}
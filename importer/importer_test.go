@@ -0,0 +1,275 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testSenderIBAN    = "TR330006100519786457841326"
+	testRecipientIBAN = "TR400006200000000000000001"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount  string
+		want    string
+		wantErr bool
+	}{
+		{"1234,56", "1234.56", false},
+		{"1.234,56", "1234.56", false},
+		{"1.234.567,5", "1234567.5", false},
+		{"1234", "1234", false},
+		{" 1 234,56 ", "1234.56", false},
+		{"1.234,56 TL", "1234.56", false},
+		{"₺1.234,56", "1234.56", false},
+		{"TRY 10", "10", false},
+		{"1.234", "1234", false},
+		{"1234.56", "", true},
+		{"1,234.56", "", true},
+		{"12.34", "", true},
+		{"1234,", "", true},
+		{"1,2,3", "", true},
+		{"abc", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.amount)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAmount(%q) error = %v, want error %v", tt.amount, err, tt.wantErr)
+			continue
+		}
+
+		if !tt.wantErr && !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("ParseAmount(%q) = %s, want %s", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestMapColumns(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         []string
+		senderRequired bool
+		want           map[Field]int
+		wantErr        bool
+	}{
+		{
+			name:   "English headers",
+			header: []string{"IBAN", "Name", "Identity Number", "Amount", "Reference"},
+			want: map[Field]int{
+				FieldRecipientIBAN: 0, FieldRecipientName: 1, FieldRecipientID: 2, FieldAmount: 3, FieldRefCode: 4,
+			},
+		},
+		{
+			name:   "Turkish headers with the dotted and dotless i",
+			header: []string{"ALICI İBAN", "Ad  Soyad", "TC KİMLİK NO", "tutar", "Açıklama"},
+			want: map[Field]int{
+				FieldRecipientIBAN: 0, FieldRecipientName: 1, FieldRecipientID: 2, FieldAmount: 3, FieldDescription: 4,
+			},
+		},
+		{
+			name:   "the first matching column",
+			header: []string{"VKN", "TCKN", "IBAN", "Unvan", "Tutar"},
+			want: map[Field]int{
+				FieldRecipientID: 0, FieldRecipientIBAN: 2, FieldRecipientName: 3, FieldAmount: 4,
+			},
+		},
+		{
+			name:           "sender IBAN column",
+			header:         []string{"Gönderen IBAN", "IBAN", "Name", "VKN", "Amount"},
+			senderRequired: true,
+			want: map[Field]int{
+				FieldSenderIBAN: 0, FieldRecipientIBAN: 1, FieldRecipientName: 2, FieldRecipientID: 3, FieldAmount: 4,
+			},
+		},
+		{
+			name:           "missing sender IBAN column",
+			header:         []string{"IBAN", "Name", "VKN", "Amount"},
+			senderRequired: true,
+			wantErr:        true,
+		},
+		{
+			name:    "missing amount column",
+			header:  []string{"IBAN", "Name", "VKN"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := &row{line: 1}
+			for _, h := range tt.header {
+				header.cells = append(header.cells, cell{value: h})
+			}
+
+			got, err := mapColumns(header, DefaultMapping, tt.senderRequired)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("mapColumns() = %v, want an error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("mapColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSVIssues(t *testing.T) {
+	content := "\ufeffIBAN;Ad Soyad;TCKN;Tutar;Referans\n" +
+		testRecipientIBAN + ";Ali Veli;10000000146;1.234,56;R1\n" +
+		"\n" +
+		"TR400006200000000000000002;Ayşe Yılmaz;12345678951;10,00;R2\n" +
+		testRecipientIBAN + ";;1234567890;-5;R3\n" +
+		testRecipientIBAN + ";Acme A.Ş.;1234567890;10,001;R4\n"
+
+	report, err := ReadCSV(strings.NewReader(content), &Options{SenderIBAN: testSenderIBAN})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.OK() {
+		t.Fatal("OK() = true, want false")
+	}
+
+	if len(report.Orders) != 1 || !reflect.DeepEqual(report.Lines, []int{2}) {
+		t.Fatalf("orders at the lines %v, want [2]", report.Lines)
+	}
+
+	o := report.Orders[0]
+	if o.RecipientIDType != corpbankclient.RecipientIDTypeNationalID || o.RefCode != "R1" ||
+		!o.TransferAmount.Equal(decimal.RequireFromString("1234.56")) {
+		t.Fatalf("unexpected order: %+v", o)
+	}
+
+	want := []struct {
+		line  int
+		field Field
+	}{
+		{4, FieldRecipientIBAN},
+		{4, FieldRecipientID},
+		{5, FieldRecipientName},
+		{5, FieldAmount},
+		{6, FieldAmount},
+	}
+
+	if len(report.Issues) != len(want) {
+		t.Fatalf("issues:\n%s", report)
+	}
+
+	for i, w := range want {
+		if got := report.Issues[i]; got.Line != w.line || got.Field != w.field {
+			t.Errorf("issue %d = %s, want line %d: %s", i, got, w.line, w.field)
+		}
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	// the header and the names are shared strings, the IBANs are inline strings, and the identity
+	// numbers and the amounts are numeric cells
+	sheet := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="2"><c r="B2" t="s"><v>0</v></c><c r="C2" t="s"><v>1</v></c><c r="D2" t="s"><v>2</v></c><c r="E2" t="s"><v>3</v></c></row>` +
+		`<row r="3">` + xlsxInline("B3", testRecipientIBAN) + `<c r="C3" t="s"><v>4</v></c><c r="D3"><v>10000000146</v></c><c r="E3"><v>1234.5599999999999</v></c></row>` +
+		`<row r="4">` + xlsxInline("B4", testRecipientIBAN) + `<c r="C4" t="s"><v>5</v></c><c r="D4" t="n"><v>1</v></c><c r="E4"><v>10</v></c></row>` +
+		`<row r="5">` + xlsxInline("B5", testRecipientIBAN) + `<c r="C5" t="s"><v>5</v></c><c r="D5"><v>1.2345678901E10</v></c><c r="E5"><v>10</v></c></row>` +
+		`<row r="6">` + xlsxInline("B6", testRecipientIBAN) + `<c r="C6" t="s"><v>5</v></c><c r="D6"><v>1234567890.5</v></c><c r="E6"><v>10</v></c></row>` +
+		`</sheetData></worksheet>`
+
+	sst := `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<si><t>IBAN</t></si><si><t>Ad Soyad</t></si><si><r><t>Kimlik </t></r><r><t>No</t></r></si><si><t>Tutar</t></si>` +
+		`<si><t>Ali Veli</t></si><si><t>Acme A.Ş.</t></si></sst>`
+
+	data := testXLSX(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Liste" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     sst,
+		"xl/worksheets/sheet1.xml": sheet,
+	})
+
+	report, err := ReadXLSX(bytes.NewReader(data), int64(len(data)), &Options{SenderIBAN: testSenderIBAN, Sheet: "Liste"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(report.Lines, []int{3, 4}) {
+		t.Fatalf("orders at the lines %v, want [3 4]\n%s", report.Lines, report)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		idType corpbankclient.RecipientIDType
+		amount string
+	}{
+		{"Ali Veli", "10000000146", corpbankclient.RecipientIDTypeNationalID, "1234.56"},
+		{"Acme A.Ş.", "0000000001", corpbankclient.RecipientIDTypeTaxID, "10"},
+	}
+
+	for i, tt := range tests {
+		o := report.Orders[i]
+
+		if o.RecipientName != tt.name || o.RecipientIBAN != testRecipientIBAN || o.RecipientIdentityNum != tt.id ||
+			o.RecipientIDType != tt.idType || !o.TransferAmount.Equal(decimal.RequireFromString(tt.amount)) {
+			t.Errorf("order %d = %+v, want %s, %s %s, %s", i, o, tt.name, tt.idType, tt.id, tt.amount)
+		}
+	}
+
+	if len(report.Issues) != 2 {
+		t.Fatalf("issues:\n%s", report)
+	}
+
+	for i, line := range []int{5, 6} {
+		if got := report.Issues[i]; got.Line != line || got.Field != FieldRecipientID {
+			t.Errorf("issue %d = %s, want line %d: %s", i, got, line, FieldRecipientID)
+		}
+	}
+
+	if _, err := ReadXLSX(bytes.NewReader(data), int64(len(data)), &Options{Sheet: "Sayfa1"}); err == nil {
+		t.Error("ReadXLSX() of a missing sheet = nil, want an error")
+	}
+}
+
+func xlsxInline(ref, s string) string {
+	return `<c r="` + ref + `" t="inlineStr"><is><t>` + s + `</t></is></c>`
+}
+
+func testXLSX(t *testing.T, parts map[string]string) []byte {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string   `xml:"r,attr"`
+			T      string   `xml:"t,attr"`
+			V      string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX imports the sheet of the Excel workbook. The first non-empty row is the header.
+func ReadXLSX(r io.ReaderAt, size int64, opts *Options) (*Report, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open the XLSX file")
+	}
+
+	sheetName := ""
+	if opts != nil {
+		sheetName = opts.Sheet
+	}
+
+	sheetPath, err := xlsxSheetPath(zr, sheetName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sst := &xlsxSharedStrings{}
+	if err := readXMLPart(zr, "xl/sharedStrings.xml", sst); err != nil && !errors.Is(err, errPartNotFound) {
+		return nil, errors.WithStack(err)
+	}
+
+	sheet := &xlsxSheet{}
+	if err := readXMLPart(zr, sheetPath, sheet); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rows := make([]*row, 0, len(sheet.Rows))
+
+	for i, sr := range sheet.Rows {
+		line := sr.R
+		if line == 0 {
+			line = i + 1
+		}
+
+		r := &row{line: line}
+
+		for j, sc := range sr.Cells {
+			col := j
+			if sc.R != "" {
+				if col, err = columnIndex(sc.R); err != nil {
+					return nil, errors.WithStack(err)
+				}
+			}
+
+			c := cell{}
+
+			switch sc.T {
+			case "s":
+				idx, err := strconv.Atoi(sc.V)
+				if err != nil || idx < 0 || idx >= len(sst.Items) {
+					return nil, errors.Errorf("invalid shared string index at cell %s: `%s`", sc.R, sc.V)
+				}
+
+				c.value = sst.Items[idx].String()
+
+			case "inlineStr":
+				c.value = sc.Inline.String()
+
+			case "", "n":
+				c.value = sc.V
+				c.numeric = true
+
+			default:
+				c.value = sc.V
+			}
+
+			for len(r.cells) <= col {
+				r.cells = append(r.cells, cell{})
+			}
+
+			r.cells[col] = c
+		}
+
+		rows = append(rows, r)
+	}
+
+	// the empty rows above the header are skipped
+	for len(rows) > 0 && isEmpty(rows[0]) {
+		rows = rows[1:]
+	}
+
+	next := func() (*row, error) {
+		if len(rows) == 0 {
+			return nil, io.EOF
+		}
+
+		r := rows[0]
+		rows = rows[1:]
+
+		return r, nil
+	}
+
+	return importRows(next, opts)
+}
+
+var errPartNotFound = errors.New("XLSX part not found")
+
+func readXMLPart(zr *zip.Reader, name string, v interface{}) error {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return errors.Wrapf(err, "unable to open the XLSX part: `%s`", name)
+		}
+
+		defer rc.Close()
+
+		if err := xml.NewDecoder(rc).Decode(v); err != nil {
+			return errors.Wrapf(err, "unable to parse the XLSX part: `%s`", name)
+		}
+
+		return nil
+	}
+
+	return errors.Wrapf(errPartNotFound, "`%s`", name)
+}
+
+// xlsxSheetPath resolves the path of the sheet by its name, or the first sheet if the name is empty.
+func xlsxSheetPath(zr *zip.Reader, name string) (string, error) {
+	wb := &xlsxWorkbook{}
+	if err := readXMLPart(zr, "xl/workbook.xml", wb); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	rels := &xlsxRels{}
+	if err := readXMLPart(zr, "xl/_rels/workbook.xml.rels", rels); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, s := range wb.Sheets {
+		if name != "" && s.Name != name {
+			continue
+		}
+
+		for _, rel := range rels.Rels {
+			if rel.ID != s.RID {
+				continue
+			}
+
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+
+			return path.Join("xl", rel.Target), nil
+		}
+
+		return "", errors.Errorf("the sheet has no relationship: `%s`", s.Name)
+	}
+
+	if name == "" {
+		return "", errors.New("the workbook has no sheets")
+	}
+
+	return "", errors.Errorf("sheet not found: `%s`", name)
+}
+
+// columnIndex returns the zero-based column index of the cell reference, e.g. 27 for "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+
+		if c >= 'A' && c <= 'Z' {
+			col = col*26 + int(c-'A') + 1
+			continue
+		}
+
+		if i == 0 || c < '0' || c > '9' {
+			return 0, errors.Errorf("invalid cell reference: `%s`", ref)
+		}
+
+		break
+	}
+
+	return col - 1, nil
+}
@@ -0,0 +1,278 @@
+// Package iso20022 converts the ISO 20022 customer credit transfer initiations (pain.001) into
+// payment orders, and reports their statuses (pain.002) after they are sent.
+package iso20022
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// notProvided is the placeholder of the missing end-to-end IDs.
+const notProvided = "NOTPROVIDED"
+
+const supportedCurrency = "TRY"
+
+// ErrInvalidAmount is the error of the credit transfers with a malformed, non-positive or over-precise
+// instructed amount.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Initiation is a parsed pain.001 message.
+type Initiation struct {
+	MsgID        string
+	MsgNameID    string
+	CreationTime time.Time
+	Transfers    []CreditTransfer
+}
+
+// CreditTransfer is a credit transfer transaction of the pain.001 message, with its payment order.
+type CreditTransfer struct {
+	PmtInfID   string
+	InstrID    string
+	EndToEndID string
+	Order      corpbankclient.PaymentOrder
+
+	// Err is the reason why the transaction can not be converted into a payment order, e.g.
+	// corpbankclient.ErrCurrencyMismatch for the currencies other than TRY, ErrInvalidAmount, or
+	// corpbankclient.ErrInvalidRecipientID for an unsupported identification scheme.
+	// The transactions with an error are not sent, and they are reported as rejected.
+	Err error
+}
+
+// Orders returns the payment orders of the credit transfers without an error, in the same order.
+func (i *Initiation) Orders() []corpbankclient.PaymentOrder {
+	var orders []corpbankclient.PaymentOrder
+
+	for _, t := range i.Transfers {
+		if t.Err == nil {
+			orders = append(orders, t.Order)
+		}
+	}
+
+	return orders
+}
+
+type pain001Document struct {
+	XMLName xml.Name `xml:"Document"`
+	Initn   struct {
+		GrpHdr struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+
+		PmtInfs []struct {
+			PmtInfID    string   `xml:"PmtInfId"`
+			ReqdExctnDt dateNode `xml:"ReqdExctnDt"`
+			DbtrAcct    account  `xml:"DbtrAcct"`
+
+			Txs []struct {
+				PmtID struct {
+					InstrID    string `xml:"InstrId"`
+					EndToEndID string `xml:"EndToEndId"`
+				} `xml:"PmtId"`
+
+				Amt struct {
+					Value string `xml:",chardata"`
+					Ccy   string `xml:"Ccy,attr"`
+				} `xml:"Amt>InstdAmt"`
+
+				Cdtr struct {
+					Nm     string   `xml:"Nm"`
+					PrvtID *otherID `xml:"Id>PrvtId>Othr"`
+					OrgID  *otherID `xml:"Id>OrgId>Othr"`
+				} `xml:"Cdtr"`
+
+				CdtrAcct account `xml:"CdtrAcct"`
+
+				Ustrd []string `xml:"RmtInf>Ustrd"`
+			} `xml:"CdtTrfTxInf"`
+		} `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+// dateNode is an ISODate in pain.001.001.03, and a choice of Dt or DtTm in the later versions.
+type dateNode struct {
+	Value string `xml:",chardata"`
+	Dt    string `xml:"Dt"`
+	DtTm  string `xml:"DtTm"`
+}
+
+type account struct {
+	IBAN string `xml:"Id>IBAN"`
+	Ccy  string `xml:"Ccy"`
+}
+
+type otherID struct {
+	ID     string `xml:"Id"`
+	Scheme string `xml:"SchmeNm>Cd"`
+}
+
+// idSchemes maps the person and organisation identification scheme codes to the identifier types.
+var idSchemes = map[string]corpbankclient.RecipientIDType{
+	"NIDN": corpbankclient.RecipientIDTypeNationalID,
+	"CCPT": corpbankclient.RecipientIDTypePassport,
+	"ARNU": corpbankclient.RecipientIDTypeForeignID,
+	"TXID": corpbankclient.RecipientIDTypeTaxID,
+}
+
+// ParsePain001 parses a pain.001 customer credit transfer initiation message. The versions from
+// pain.001.001.03 to pain.001.001.11 are supported, since only their common elements are read:
+//
+//	DbtrAcct/Id/IBAN            SenderIBAN
+//	CdtrAcct/Id/IBAN            RecipientIBAN
+//	Cdtr/Nm                     RecipientName
+//	Cdtr/Id/PrvtId|OrgId/Othr   RecipientIdentityNum and RecipientIDType, by the scheme code
+//	                            (NIDN, CCPT, ARNU or TXID)
+//	InstdAmt                    TransferAmount, only in TRY
+//	PmtId/EndToEndId            RefCode, or PmtId/InstrId if it is not provided
+//	RmtInf/Ustrd                Description
+//	ReqdExctnDt                 ExecutionDate, if it is later than today
+//
+// The errors of the individual transactions, e.g. an unsupported currency, are reported by their Err
+// fields instead of failing the whole message.
+func ParsePain001(r io.Reader) (*Initiation, error) {
+	doc := &pain001Document{}
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the pain.001 message")
+	}
+
+	if doc.XMLName.Space != "" && !strings.Contains(doc.XMLName.Space, "pain.001") {
+		return nil, errors.Errorf("not a pain.001 message: `%s`", doc.XMLName.Space)
+	}
+
+	initn := &doc.Initn
+
+	if initn.GrpHdr.MsgID == "" {
+		return nil, errors.New("missing GrpHdr/MsgId in the pain.001 message")
+	}
+
+	init := &Initiation{
+		MsgID:     initn.GrpHdr.MsgID,
+		MsgNameID: msgNameID(doc.XMLName.Space, "pain.001.001.03"),
+	}
+
+	if _, ok := pain002Versions[init.MsgNameID]; !ok {
+		return nil, errors.Errorf("unsupported version of the pain.001 message: `%s`", init.MsgNameID)
+	}
+
+	if initn.GrpHdr.CreDtTm != "" {
+		t, err := parseDateTime(initn.GrpHdr.CreDtTm)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid GrpHdr/CreDtTm: `%s`", initn.GrpHdr.CreDtTm)
+		}
+
+		init.CreationTime = t
+	}
+
+	today := time.Now().Format(isoDateLayout)
+
+	for _, pmtInf := range initn.PmtInfs {
+		var executionDate time.Time
+
+		if d := pmtInf.ReqdExctnDt.date(); d > today {
+			t, err := time.ParseInLocation(isoDateLayout, d, time.Local)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid ReqdExctnDt of the payment information `%s`", pmtInf.PmtInfID)
+			}
+
+			executionDate = t
+		}
+
+		for _, tx := range pmtInf.Txs {
+			ct := CreditTransfer{
+				PmtInfID:   pmtInf.PmtInfID,
+				InstrID:    tx.PmtID.InstrID,
+				EndToEndID: tx.PmtID.EndToEndID,
+			}
+
+			amount, err := decimal.NewFromString(strings.TrimSpace(tx.Amt.Value))
+
+			switch {
+			case tx.Amt.Ccy != supportedCurrency:
+				ct.Err = errors.Wrapf(corpbankclient.ErrCurrencyMismatch, "unsupported currency: `%s`", tx.Amt.Ccy)
+			case err != nil:
+				ct.Err = errors.Wrapf(ErrInvalidAmount, "not a decimal number: `%s`", tx.Amt.Value)
+			case !amount.IsPositive():
+				ct.Err = errors.Wrapf(ErrInvalidAmount, "the amount must be positive: `%s`", tx.Amt.Value)
+			case !amount.Equal(amount.Round(2)):
+				ct.Err = errors.Wrapf(ErrInvalidAmount, "more than 2 decimal places: `%s`", tx.Amt.Value)
+			}
+
+			refCode := ct.EndToEndID
+			if refCode == "" || refCode == notProvided {
+				refCode = ct.InstrID
+			}
+
+			ct.Order = corpbankclient.PaymentOrder{
+				SenderIBAN:     pmtInf.DbtrAcct.IBAN,
+				RecipientIBAN:  tx.CdtrAcct.IBAN,
+				RecipientName:  tx.Cdtr.Nm,
+				TransferAmount: amount,
+				RefCode:        refCode,
+				Description:    strings.Join(tx.Ustrd, " "),
+				ExecutionDate:  executionDate,
+			}
+
+			id := tx.Cdtr.PrvtID
+			if id == nil {
+				id = tx.Cdtr.OrgID
+			}
+
+			if id != nil {
+				ct.Order.RecipientIdentityNum = id.ID
+
+				idType, ok := idSchemes[id.Scheme]
+				if !ok && ct.Err == nil {
+					ct.Err = errors.Wrapf(corpbankclient.ErrInvalidRecipientID, "unsupported identification scheme: `%s`", id.Scheme)
+				}
+
+				ct.Order.RecipientIDType = idType
+			}
+
+			init.Transfers = append(init.Transfers, ct)
+		}
+	}
+
+	return init, nil
+}
+
+const (
+	isoDateLayout     = "2006-01-02"
+	isoDateTimeLayout = "2006-01-02T15:04:05"
+)
+
+func (d dateNode) date() string {
+	switch {
+	case d.Dt != "":
+		return d.Dt
+	case len(d.DtTm) >= len(isoDateLayout):
+		return d.DtTm[:len(isoDateLayout)]
+	default:
+		return strings.TrimSpace(d.Value)
+	}
+}
+
+// parseDateTime parses an ISODateTime, which may omit the time zone.
+func parseDateTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(isoDateTimeLayout, strings.SplitN(s, ".", 2)[0], time.Local)
+	return t, errors.WithStack(err)
+}
+
+// msgNameID extracts the message name identification from the namespace,
+// e.g. "pain.001.001.09" from "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09".
+func msgNameID(namespace, fallback string) string {
+	if i := strings.LastIndex(namespace, ":"); i >= 0 && i < len(namespace)-1 {
+		return namespace[i+1:]
+	}
+
+	return fallback
+}
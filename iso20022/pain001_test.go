@@ -0,0 +1,189 @@
+package iso20022
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+)
+
+const (
+	testDebtorIBAN   = "TR330006100519786457841326"
+	testCreditorIBAN = "TR400006200000000000000001"
+)
+
+type testTx struct {
+	instrID, endToEndID string
+	amount, ccy         string
+	idElement, scheme   string
+	id                  string
+}
+
+func (tx testTx) xml() string {
+	cdtrID := ""
+	if tx.scheme != "" {
+		cdtrID = fmt.Sprintf(`<Id><%s><Othr><Id>%s</Id><SchmeNm><Cd>%s</Cd></SchmeNm></Othr></%s></Id>`,
+			tx.idElement, tx.id, tx.scheme, tx.idElement)
+	}
+
+	return fmt.Sprintf(`<CdtTrfTxInf>
+  <PmtId><InstrId>%s</InstrId><EndToEndId>%s</EndToEndId></PmtId>
+  <Amt><InstdAmt Ccy="%s">%s</InstdAmt></Amt>
+  <Cdtr><Nm>Ali Veli</Nm>%s</Cdtr>
+  <CdtrAcct><Id><IBAN>%s</IBAN></Id></CdtrAcct>
+  <RmtInf><Ustrd>Fatura</Ustrd><Ustrd>2024/01</Ustrd></RmtInf>
+</CdtTrfTxInf>`, tx.instrID, tx.endToEndID, tx.ccy, tx.amount, cdtrID, testCreditorIBAN)
+}
+
+func testPain001(version, executionDate string, txs ...testTx) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:%s">
+<CstmrCdtTrfInitn>
+<GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2024-01-02T10:00:00</CreDtTm></GrpHdr>
+<PmtInf>
+<PmtInfId>PMT-1</PmtInfId>
+<ReqdExctnDt>%s</ReqdExctnDt>
+<DbtrAcct><Id><IBAN>%s</IBAN></Id></DbtrAcct>
+`, version, executionDate, testDebtorIBAN)
+
+	for _, tx := range txs {
+		b.WriteString(tx.xml())
+	}
+
+	b.WriteString("</PmtInf>\n</CstmrCdtTrfInitn>\n</Document>")
+
+	return b.String()
+}
+
+var validTx = testTx{"I-1", "E2E-1", "100.50", "TRY", "PrvtId", "NIDN", "10000000146"}
+
+func TestParsePain001Versions(t *testing.T) {
+	scheduled := time.Date(2099, time.January, 2, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name          string
+		version       string
+		executionDate string
+		want          time.Time
+	}{
+		{"ISODate", "pain.001.001.03", "2099-01-02", scheduled},
+		{"Dt", "pain.001.001.09", "<Dt>2099-01-02</Dt>", scheduled},
+		{"DtTm", "pain.001.001.11", "<DtTm>2099-01-02T10:00:00</DtTm>", scheduled},
+		{"past date", "pain.001.001.09", "<Dt>2000-01-01</Dt>", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			init, err := ParsePain001(strings.NewReader(testPain001(tt.version, tt.executionDate, validTx)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if init.MsgID != "MSG-1" || init.MsgNameID != tt.version {
+				t.Fatalf("message = %s %s, want MSG-1 %s", init.MsgID, init.MsgNameID, tt.version)
+			}
+
+			if want := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.Local); !init.CreationTime.Equal(want) {
+				t.Fatalf("CreationTime = %s, want %s", init.CreationTime, want)
+			}
+
+			if len(init.Transfers) != 1 {
+				t.Fatalf("%d transfers, want 1", len(init.Transfers))
+			}
+
+			ct := init.Transfers[0]
+			if ct.Err != nil {
+				t.Fatal(ct.Err)
+			}
+
+			if !ct.Order.ExecutionDate.Equal(tt.want) {
+				t.Fatalf("ExecutionDate = %s, want %s", ct.Order.ExecutionDate, tt.want)
+			}
+
+			o := ct.Order
+			if ct.PmtInfID != "PMT-1" || o.SenderIBAN != testDebtorIBAN || o.RecipientIBAN != testCreditorIBAN ||
+				o.RecipientName != "Ali Veli" || o.RecipientIdentityNum != "10000000146" ||
+				o.RecipientIDType != corpbankclient.RecipientIDTypeNationalID ||
+				o.TransferAmount.String() != "100.5" || o.RefCode != "E2E-1" || o.Description != "Fatura 2024/01" {
+				t.Fatalf("unexpected transfer: %+v", ct)
+			}
+		})
+	}
+}
+
+func TestParsePain001Unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"unsupported version", testPain001("pain.001.001.02", "2024-01-02", validTx)},
+		{"not pain.001", strings.Replace(testPain001("pain.001.001.03", "2024-01-02", validTx), "pain.001.001.03", "pain.008.001.02", 1)},
+		{"missing MsgId", strings.Replace(testPain001("pain.001.001.03", "2024-01-02", validTx), "<MsgId>MSG-1</MsgId>", "", 1)},
+		{"malformed", "<Document>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePain001(strings.NewReader(tt.message)); err == nil {
+				t.Fatal("ParsePain001() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestParsePain001Transfers(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      testTx
+		refCode string
+		idType  corpbankclient.RecipientIDType
+		wantErr error
+	}{
+		{"valid", validTx, "E2E-1", corpbankclient.RecipientIDTypeNationalID, nil},
+		{"InstrId fallback of NOTPROVIDED", testTx{"I-2", "NOTPROVIDED", "10", "TRY", "OrgId", "TXID", "1234567890"}, "I-2", corpbankclient.RecipientIDTypeTaxID, nil},
+		{"InstrId fallback of an empty EndToEndId", testTx{"I-3", "", "10", "TRY", "", "", ""}, "I-3", "", nil},
+		{"unsupported currency", testTx{"I-4", "E2E-4", "10", "USD", "PrvtId", "NIDN", "10000000146"}, "E2E-4", corpbankclient.RecipientIDTypeNationalID, corpbankclient.ErrCurrencyMismatch},
+		{"unsupported scheme", testTx{"I-5", "E2E-5", "10", "TRY", "PrvtId", "DRLC", "123"}, "E2E-5", "", corpbankclient.ErrInvalidRecipientID},
+		{"zero amount", testTx{"I-6", "E2E-6", "0", "TRY", "", "", ""}, "E2E-6", "", ErrInvalidAmount},
+		{"negative amount", testTx{"I-7", "E2E-7", "-10", "TRY", "", "", ""}, "E2E-7", "", ErrInvalidAmount},
+		{"over-precise amount", testTx{"I-8", "E2E-8", "10.005", "TRY", "", "", ""}, "E2E-8", "", ErrInvalidAmount},
+		{"malformed amount", testTx{"I-9", "E2E-9", "10,5", "TRY", "", "", ""}, "E2E-9", "", ErrInvalidAmount},
+	}
+
+	txs := make([]testTx, len(tests))
+	for i, tt := range tests {
+		txs[i] = tt.tx
+	}
+
+	init, err := ParsePain001(strings.NewReader(testPain001("pain.001.001.09", "<Dt>2024-01-02</Dt>", txs...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(init.Transfers) != len(tests) {
+		t.Fatalf("%d transfers, want %d", len(init.Transfers), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := init.Transfers[i]
+
+			if !errors.Is(ct.Err, tt.wantErr) || (tt.wantErr == nil && ct.Err != nil) {
+				t.Fatalf("Err = %v, want %v", ct.Err, tt.wantErr)
+			}
+
+			if ct.Order.RefCode != tt.refCode || ct.Order.RecipientIDType != tt.idType {
+				t.Fatalf("RefCode, RecipientIDType = %s, %s, want %s, %s", ct.Order.RefCode, ct.Order.RecipientIDType, tt.refCode, tt.idType)
+			}
+		})
+	}
+
+	if orders := init.Orders(); len(orders) != 3 || orders[1].RefCode != "I-2" {
+		t.Fatalf("Orders() = %v, want the 3 transfers without an error", orders)
+	}
+}
@@ -0,0 +1,234 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const namespacePrefix = "urn:iso:std:iso:20022:tech:xsd:"
+
+// pain002Versions are the versions of the pain.002 status reports, by the versions of the pain.001
+// messages they report on. The elements written by WritePain002 are common to all of them.
+var pain002Versions = map[string]string{
+	"pain.001.001.03": "pain.002.001.03",
+	"pain.001.001.04": "pain.002.001.04",
+	"pain.001.001.05": "pain.002.001.05",
+	"pain.001.001.06": "pain.002.001.06",
+	"pain.001.001.07": "pain.002.001.07",
+	"pain.001.001.08": "pain.002.001.08",
+	"pain.001.001.09": "pain.002.001.10",
+	"pain.001.001.10": "pain.002.001.11",
+	"pain.001.001.11": "pain.002.001.12",
+}
+
+// Transaction and group status codes of the pain.002 messages.
+const (
+	StatusAcceptedSettlementInProcess = "ACSP"
+	StatusPending                     = "PDNG"
+	StatusRejected                    = "RJCT"
+	StatusPartiallyAccepted           = "PART"
+)
+
+// notProcessed is the additional information of the credit transfers without a batch result item,
+// or with an item without a result or an error, which are reported as rejected.
+const notProcessed = "not processed"
+
+// maxAdditionalInfoLen is the maximum length of the AddtlInf elements.
+const maxAdditionalInfoLen = 105
+
+// reasonCodes map the errors to the ISO 20022 external status reason codes.
+var reasonCodes = []struct {
+	err  error
+	code string
+}{
+	{corpbankclient.ErrInsufficientBalance, "AM04"},
+	{corpbankclient.ErrCurrencyMismatch, "AM03"},
+	{ErrInvalidAmount, "FF01"},
+	{corpbankclient.ErrIdempotencyKeyReused, "AM05"},
+	{corpbankclient.ErrAccountNotFound, "AC02"},
+	{corpbankclient.ErrIncorrectRecipientData, "BE01"},
+	{corpbankclient.ErrInvalidRecipientID, "BE17"},
+	{corpbankclient.ErrOutOfEFTHours, "TM01"},
+	{corpbankclient.ErrUnauthorized, "AG01"},
+	{corpbankclient.ErrForbidden, "AG01"},
+}
+
+// narrativeReason is the reason code of the errors without a specific code.
+const narrativeReason = "NARR"
+
+type pain002Document struct {
+	XMLName xml.Name `xml:"Document"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Rpt     struct {
+		GrpHdr struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+
+		OrgnlGrpInfAndSts struct {
+			OrgnlMsgID   string `xml:"OrgnlMsgId"`
+			OrgnlMsgNmID string `xml:"OrgnlMsgNmId"`
+			OrgnlNbOfTxs string `xml:"OrgnlNbOfTxs"`
+			GrpSts       string `xml:"GrpSts"`
+		} `xml:"OrgnlGrpInfAndSts"`
+
+		PmtInfs []*pain002PmtInf `xml:"OrgnlPmtInfAndSts"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+type pain002PmtInf struct {
+	OrgnlPmtInfID string         `xml:"OrgnlPmtInfId"`
+	Txs           []pain002TxSts `xml:"TxInfAndSts"`
+}
+
+type pain002TxSts struct {
+	OrgnlInstrID    string         `xml:"OrgnlInstrId,omitempty"`
+	OrgnlEndToEndID string         `xml:"OrgnlEndToEndId,omitempty"`
+	TxSts           string         `xml:"TxSts"`
+	StsRsnInf       *pain002Reason `xml:"StsRsnInf,omitempty"`
+	AcctSvcrRef     string         `xml:"AcctSvcrRef,omitempty"`
+}
+
+type pain002Reason struct {
+	Code     string `xml:"Rsn>Cd"`
+	AddtlInf string `xml:"AddtlInf,omitempty"`
+}
+
+// WritePain002 writes the pain.002 status report of the credit transfers, by the result of
+// MakePayments for the orders of the initiation. The items of the batch result are matched to
+// the credit transfers without an error by their indexes, as returned by Orders. The succeeded
+// payments are reported as accepted with their payment IDs, and the failed ones and the credit
+// transfers with an error as rejected with the reason codes of their errors. The credit transfers
+// which are not processed, e.g. after the batch is canceled, are rejected with NARR. The version of the report
+// matches the version of the initiation, e.g. pain.002.001.10 for pain.001.001.09.
+func WritePain002(w io.Writer, init *Initiation, result *corpbankclient.BatchResult) error {
+	version, ok := pain002Versions[init.MsgNameID]
+	if !ok {
+		return errors.Errorf("unsupported version of the pain.001 message: `%s`", init.MsgNameID)
+	}
+
+	doc := &pain002Document{Xmlns: namespacePrefix + version}
+
+	doc.Rpt.GrpHdr.MsgID = strings.ReplaceAll(uuid.New().String(), "-", "")
+	doc.Rpt.GrpHdr.CreDtTm = time.Now().Format(isoDateTimeLayout)
+
+	doc.Rpt.OrgnlGrpInfAndSts.OrgnlMsgID = init.MsgID
+	doc.Rpt.OrgnlGrpInfAndSts.OrgnlMsgNmID = init.MsgNameID
+	doc.Rpt.OrgnlGrpInfAndSts.OrgnlNbOfTxs = strconv.Itoa(len(init.Transfers))
+
+	pmtInfs := map[string]*pain002PmtInf{}
+	accepted, rejected := 0, 0
+
+	// i is the index of the payment order of the credit transfer
+	i := 0
+
+	for _, ct := range init.Transfers {
+		pmtInf, ok := pmtInfs[ct.PmtInfID]
+		if !ok {
+			pmtInf = &pain002PmtInf{OrgnlPmtInfID: ct.PmtInfID}
+			pmtInfs[ct.PmtInfID] = pmtInf
+			doc.Rpt.PmtInfs = append(doc.Rpt.PmtInfs, pmtInf)
+		}
+
+		tx := pain002TxSts{
+			OrgnlInstrID:    ct.InstrID,
+			OrgnlEndToEndID: ct.EndToEndID,
+		}
+
+		var item *corpbankclient.BatchItemResult
+
+		switch {
+		case ct.Err != nil:
+			item = &corpbankclient.BatchItemResult{Err: ct.Err}
+		case result != nil && i < len(result.Items):
+			item = &result.Items[i]
+		}
+
+		if ct.Err == nil {
+			i++
+		}
+
+		switch {
+		case item != nil && item.Err != nil:
+			tx.TxSts = StatusRejected
+			tx.StsRsnInf = &pain002Reason{
+				Code:     reasonCode(item.Err),
+				AddtlInf: truncate(reasonInfo(item.Err), maxAdditionalInfoLen),
+			}
+
+			rejected++
+
+		case item != nil && item.Result != nil:
+			tx.TxSts = StatusAcceptedSettlementInProcess
+			tx.AcctSvcrRef = item.Result.PaymentID.String()
+
+			accepted++
+
+		default:
+			tx.TxSts = StatusRejected
+			tx.StsRsnInf = &pain002Reason{Code: narrativeReason, AddtlInf: notProcessed}
+
+			rejected++
+		}
+
+		pmtInf.Txs = append(pmtInf.Txs, tx)
+	}
+
+	switch {
+	case rejected == 0:
+		doc.Rpt.OrgnlGrpInfAndSts.GrpSts = StatusAcceptedSettlementInProcess
+	case accepted == 0:
+		doc.Rpt.OrgnlGrpInfAndSts.GrpSts = StatusRejected
+	default:
+		doc.Rpt.OrgnlGrpInfAndSts.GrpSts = StatusPartiallyAccepted
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.WithStack(err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return errors.Wrap(err, "unable to write the pain.002 message")
+	}
+
+	return nil
+}
+
+func reasonCode(err error) string {
+	for _, r := range reasonCodes {
+		if errors.Is(err, r.err) {
+			return r.code
+		}
+	}
+
+	return narrativeReason
+}
+
+// reasonInfo prefers the error of the remote service, since the wrapping messages are too long to fit.
+func reasonInfo(err error) string {
+	var apiErr *corpbankclient.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Error()
+	}
+
+	return err.Error()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
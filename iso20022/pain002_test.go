@@ -0,0 +1,182 @@
+package iso20022
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func readPain002(t *testing.T, init *Initiation, result *corpbankclient.BatchResult) *pain002Document {
+	t.Helper()
+
+	var b bytes.Buffer
+
+	if err := WritePain002(&b, init, result); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &pain002Document{}
+	if err := xml.Unmarshal(b.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+func accepted(id uuid.UUID) corpbankclient.BatchItemResult {
+	return corpbankclient.BatchItemResult{Result: &corpbankclient.PaymentResult{PaymentID: id}}
+}
+
+func failed(err error) corpbankclient.BatchItemResult {
+	return corpbankclient.BatchItemResult{Err: err}
+}
+
+func TestWritePain002(t *testing.T) {
+	init := &Initiation{
+		MsgID:     "MSG-1",
+		MsgNameID: "pain.001.001.09",
+		Transfers: []CreditTransfer{
+			{PmtInfID: "PMT-1", InstrID: "I-1", EndToEndID: "E2E-1"},
+			{PmtInfID: "PMT-1", InstrID: "I-2", EndToEndID: "E2E-2", Err: errors.Wrap(corpbankclient.ErrCurrencyMismatch, "unsupported currency")},
+			{PmtInfID: "PMT-2", InstrID: "I-3", EndToEndID: "E2E-3"},
+			{PmtInfID: "PMT-2", InstrID: "I-4", EndToEndID: "E2E-4", Err: errors.Wrap(ErrInvalidAmount, "more than 2 decimal places")},
+			{PmtInfID: "PMT-2", InstrID: "I-5", EndToEndID: "E2E-5"},
+		},
+	}
+
+	paymentID := uuid.New()
+
+	// the items are matched to the transfers without an error, and the last one is not processed
+	result := &corpbankclient.BatchResult{Items: []corpbankclient.BatchItemResult{
+		accepted(paymentID),
+		failed(errors.Wrap(corpbankclient.ErrInsufficientBalance, "unable to make the payment")),
+	}}
+
+	doc := readPain002(t, init, result)
+
+	if doc.XMLName.Space != namespacePrefix+"pain.002.001.10" {
+		t.Errorf("namespace = %s, want pain.002.001.10", doc.XMLName.Space)
+	}
+
+	grp := doc.Rpt.OrgnlGrpInfAndSts
+	if grp.OrgnlMsgID != "MSG-1" || grp.OrgnlMsgNmID != "pain.001.001.09" || grp.OrgnlNbOfTxs != "5" || grp.GrpSts != StatusPartiallyAccepted {
+		t.Errorf("unexpected group status: %+v", grp)
+	}
+
+	want := []struct {
+		pmtInfID string
+		instrID  string
+		status   string
+		reason   string
+	}{
+		{"PMT-1", "I-1", StatusAcceptedSettlementInProcess, ""},
+		{"PMT-1", "I-2", StatusRejected, "AM03"},
+		{"PMT-2", "I-3", StatusRejected, "AM04"},
+		{"PMT-2", "I-4", StatusRejected, "FF01"},
+		{"PMT-2", "I-5", StatusRejected, narrativeReason},
+	}
+
+	var got []pain002TxSts
+	var pmtInfIDs []string
+
+	for _, pmtInf := range doc.Rpt.PmtInfs {
+		for _, tx := range pmtInf.Txs {
+			got = append(got, tx)
+			pmtInfIDs = append(pmtInfIDs, pmtInf.OrgnlPmtInfID)
+		}
+	}
+
+	if len(doc.Rpt.PmtInfs) != 2 || len(got) != len(want) {
+		t.Fatalf("%d payment information blocks with %d transactions, want 2 with %d", len(doc.Rpt.PmtInfs), len(got), len(want))
+	}
+
+	for i, w := range want {
+		tx := got[i]
+
+		reason := ""
+		if tx.StsRsnInf != nil {
+			reason = tx.StsRsnInf.Code
+		}
+
+		if pmtInfIDs[i] != w.pmtInfID || tx.OrgnlInstrID != w.instrID || tx.TxSts != w.status || reason != w.reason {
+			t.Errorf("transaction %d = %s %s %s %s, want %s %s %s %s", i,
+				pmtInfIDs[i], tx.OrgnlInstrID, tx.TxSts, reason, w.pmtInfID, w.instrID, w.status, w.reason)
+		}
+	}
+
+	if got[0].AcctSvcrRef != paymentID.String() {
+		t.Errorf("AcctSvcrRef = %s, want %s", got[0].AcctSvcrRef, paymentID)
+	}
+
+	if got[4].StsRsnInf.AddtlInf != notProcessed {
+		t.Errorf("AddtlInf = %s, want %s", got[4].StsRsnInf.AddtlInf, notProcessed)
+	}
+}
+
+func TestWritePain002GroupStatus(t *testing.T) {
+	init := &Initiation{
+		MsgID:     "MSG-1",
+		MsgNameID: "pain.001.001.03",
+		Transfers: []CreditTransfer{{InstrID: "I-1"}, {InstrID: "I-2"}},
+	}
+
+	tests := []struct {
+		name   string
+		result *corpbankclient.BatchResult
+		want   string
+	}{
+		{"all accepted", &corpbankclient.BatchResult{Items: []corpbankclient.BatchItemResult{accepted(uuid.New()), accepted(uuid.New())}}, StatusAcceptedSettlementInProcess},
+		{"partially accepted", &corpbankclient.BatchResult{Items: []corpbankclient.BatchItemResult{accepted(uuid.New()), failed(corpbankclient.ErrOutOfEFTHours)}}, StatusPartiallyAccepted},
+		{"all rejected", &corpbankclient.BatchResult{Items: []corpbankclient.BatchItemResult{failed(corpbankclient.ErrForbidden), failed(corpbankclient.ErrOutOfEFTHours)}}, StatusRejected},
+		{"not processed", nil, StatusRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := readPain002(t, init, tt.result)
+
+			if got := doc.Rpt.OrgnlGrpInfAndSts.GrpSts; got != tt.want {
+				t.Fatalf("GrpSts = %s, want %s", got, tt.want)
+			}
+
+			if doc.XMLName.Space != namespacePrefix+"pain.002.001.03" {
+				t.Fatalf("namespace = %s, want pain.002.001.03", doc.XMLName.Space)
+			}
+		})
+	}
+
+	init.MsgNameID = "pain.001.001.02"
+	if err := WritePain002(&bytes.Buffer{}, init, nil); err == nil {
+		t.Fatal("WritePain002() of an unsupported version = nil, want an error")
+	}
+}
+
+func TestReasonCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{corpbankclient.ErrInsufficientBalance, "AM04"},
+		{corpbankclient.ErrCurrencyMismatch, "AM03"},
+		{errors.Wrap(ErrInvalidAmount, "zero"), "FF01"},
+		{corpbankclient.ErrIdempotencyKeyReused, "AM05"},
+		{corpbankclient.ErrAccountNotFound, "AC02"},
+		{corpbankclient.ErrIncorrectRecipientData, "BE01"},
+		{corpbankclient.ErrInvalidRecipientID, "BE17"},
+		{corpbankclient.ErrOutOfEFTHours, "TM01"},
+		{corpbankclient.ErrUnauthorized, "AG01"},
+		{corpbankclient.ErrForbidden, "AG01"},
+		{corpbankclient.ErrNotFound, narrativeReason},
+		{errors.New("connection reset"), narrativeReason},
+	}
+
+	for _, tt := range tests {
+		if got := reasonCode(tt.err); got != tt.want {
+			t.Errorf("reasonCode(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
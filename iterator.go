@@ -0,0 +1,255 @@
+package corpbankclient
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+type page struct {
+	info  *PageInfo
+	items interface{}
+	err   error
+}
+
+type pageFetcher func(ctx context.Context, pageNum int) (*PageInfo, interface{}, error)
+
+// pager fetches the pages lazily in a background goroutine. The goroutine
+// blocks on sending a fetched page until the consumer asks for it, so at most
+// one page is prefetched ahead of the consumer.
+type pager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	pages  chan *page
+	done   chan struct{}
+}
+
+func newPager(ctx context.Context, fetch pageFetcher) *pager {
+	ctx, cancel := context.WithCancel(ctx)
+
+	p := &pager{
+		ctx:    ctx,
+		cancel: cancel,
+		pages:  make(chan *page),
+		done:   make(chan struct{}),
+	}
+
+	go p.run(fetch)
+
+	return p
+}
+
+func (p *pager) run(fetch pageFetcher) {
+	defer close(p.done)
+	defer close(p.pages)
+
+	for pageNum := 1; ; {
+		info, items, err := fetch(p.ctx, pageNum)
+		if err != nil && p.ctx.Err() != nil {
+			err = p.ctx.Err()
+		}
+
+		select {
+		case p.pages <- &page{info: info, items: items, err: err}:
+		case <-p.ctx.Done():
+			return
+		}
+
+		if err != nil || info.CurrentPage >= info.TotalPages || info.CurrentPage < pageNum {
+			return
+		}
+
+		pageNum = info.CurrentPage + 1
+	}
+}
+
+// next returns the next page, or nil if there are no more pages.
+func (p *pager) next() *page {
+	select {
+	case pg, ok := <-p.pages:
+		if !ok {
+			return nil
+		}
+
+		return pg
+
+	case <-p.ctx.Done():
+		return &page{err: p.ctx.Err()}
+	}
+}
+
+func (p *pager) close() {
+	p.cancel()
+	<-p.done
+}
+
+// TransactionIterator iterates over the bank transactions across all the pages.
+// The pages are fetched lazily, and the next page is prefetched in the background
+// while the current one is being consumed.
+//
+//	it := client.TransactionIterator(ctx, corpbankclient.WithPageSize(100))
+//	defer it.Close()
+//
+//	for it.Next() {
+//		t := it.Transaction()
+//		...
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type TransactionIterator struct {
+	p    *pager
+	info *PageInfo
+	page []Transaction
+	cur  Transaction
+	err  error
+	done bool
+}
+
+// TransactionIterator returns an iterator over the bank transactions. The list can be filtered
+// by the given list of RequestOption. Any page number given by WithPageNum is overridden by the iterator.
+func (c *Client) TransactionIterator(ctx context.Context, options ...RequestOption) *TransactionIterator {
+	opts := make([]RequestOption, len(options), len(options)+1)
+	copy(opts, options)
+
+	return &TransactionIterator{
+		p: newPager(ctx, func(ctx context.Context, pageNum int) (*PageInfo, interface{}, error) {
+			info, trxs, err := c.Transactions(ctx, append(opts, WithPageNum(pageNum))...)
+			return info, trxs, err
+		}),
+	}
+}
+
+// Next advances the iterator to the next transaction. It returns false when there are no more
+// transactions, an error occurs or the context is canceled.
+func (it *TransactionIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+
+		pg := it.p.next()
+		if pg == nil {
+			it.done = true
+			it.p.close()
+			return false
+		}
+
+		if pg.err != nil {
+			it.err = errors.WithStack(pg.err)
+			it.done = true
+			it.p.close()
+			return false
+		}
+
+		it.info = pg.info
+		it.page = pg.items.([]Transaction)
+	}
+
+	it.cur = it.page[0]
+	it.page = it.page[1:]
+
+	return true
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionIterator) Transaction() Transaction {
+	return it.cur
+}
+
+// PageInfo returns the pagination details of the last fetched page.
+func (it *TransactionIterator) PageInfo() *PageInfo {
+	return it.info
+}
+
+// Err returns the error, if any, that was encountered during the iteration.
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
+
+// Close stops the background prefetching. It is safe to call Close multiple times.
+func (it *TransactionIterator) Close() {
+	it.done = true
+	it.page = nil
+	it.p.close()
+}
+
+// APIKeyIterator iterates over the API keys across all the pages.
+// It works in the same way as TransactionIterator.
+type APIKeyIterator struct {
+	p    *pager
+	info *PageInfo
+	page []APIKey
+	cur  APIKey
+	err  error
+	done bool
+}
+
+// APIKeyIterator returns an iterator over the API keys. Any page number given by WithPageNum
+// is overridden by the iterator.
+func (c *Client) APIKeyIterator(ctx context.Context, options ...RequestOption) *APIKeyIterator {
+	opts := make([]RequestOption, len(options), len(options)+1)
+	copy(opts, options)
+
+	return &APIKeyIterator{
+		p: newPager(ctx, func(ctx context.Context, pageNum int) (*PageInfo, interface{}, error) {
+			info, keys, err := c.APIKeys(ctx, append(opts, WithPageNum(pageNum))...)
+			return info, keys, err
+		}),
+	}
+}
+
+// Next advances the iterator to the next API key. It returns false when there are no more
+// API keys, an error occurs or the context is canceled.
+func (it *APIKeyIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+
+		pg := it.p.next()
+		if pg == nil {
+			it.done = true
+			it.p.close()
+			return false
+		}
+
+		if pg.err != nil {
+			it.err = errors.WithStack(pg.err)
+			it.done = true
+			it.p.close()
+			return false
+		}
+
+		it.info = pg.info
+		it.page = pg.items.([]APIKey)
+	}
+
+	it.cur = it.page[0]
+	it.page = it.page[1:]
+
+	return true
+}
+
+// APIKey returns the current API key.
+func (it *APIKeyIterator) APIKey() APIKey {
+	return it.cur
+}
+
+// PageInfo returns the pagination details of the last fetched page.
+func (it *APIKeyIterator) PageInfo() *PageInfo {
+	return it.info
+}
+
+// Err returns the error, if any, that was encountered during the iteration.
+func (it *APIKeyIterator) Err() error {
+	return it.err
+}
+
+// Close stops the background prefetching. It is safe to call Close multiple times.
+func (it *APIKeyIterator) Close() {
+	it.done = true
+	it.page = nil
+	it.p.close()
+}
@@ -3,6 +3,7 @@ package corpbankclient
 import (
 	"time"
 
+	"github.com/birapi/go-corpbankclient/iban"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
@@ -11,6 +12,8 @@ type (
 	AuthUserStatus    string
 	TrxDirection      string
 	TrxTransferMethod string
+	PaymentStatus     string
+	RecipientIDType   string
 )
 
 const (
@@ -25,6 +28,16 @@ const (
 	TrxTransferMethodHavale TrxTransferMethod = "HAVALE"
 	TrxTransferMethodEFT    TrxTransferMethod = "EFT"
 	TrxTransferMethodFAST   TrxTransferMethod = "FAST"
+
+	PaymentStatusPending  PaymentStatus = "PENDING"
+	PaymentStatusSent     PaymentStatus = "SENT"
+	PaymentStatusFailed   PaymentStatus = "FAILED"
+	PaymentStatusRejected PaymentStatus = "REJECTED"
+
+	RecipientIDTypeNationalID RecipientIDType = "NATIONAL_ID"
+	RecipientIDTypeTaxID      RecipientIDType = "TAX_ID"
+	RecipientIDTypeForeignID  RecipientIDType = "FOREIGN_ID"
+	RecipientIDTypePassport   RecipientIDType = "PASSPORT"
 )
 
 type Credentials struct {
@@ -66,6 +79,18 @@ type TransactionParticipant struct {
 	Name           string `json:"name"`
 }
 
+// BankName returns the name of the bank by its code, or an empty string if the bank is unknown.
+func (a TransactionAccount) BankName() string {
+	name, _ := iban.BankName(a.BankCode)
+	return name
+}
+
+// BankName returns the name of the bank by its code, or an empty string if the bank is unknown.
+func (p TransactionParticipant) BankName() string {
+	name, _ := iban.BankName(p.BankCode)
+	return name
+}
+
 type Transaction struct {
 	ID             uuid.UUID               `json:"id"`
 	Date           time.Time               `json:"date"`
@@ -120,12 +145,54 @@ type PaymentOrder struct {
 	TransferAmount       decimal.Decimal
 	RefCode              string
 	Description          string
+
+	// CallbackURL receives the status notifications of the payment. Defaults to the
+	// DefaultCallbackURL of the ClientOptions.
+	CallbackURL string
+
+	// ExecutionDate schedules the payment to a future date. The payment is executed
+	// immediately if it is zero.
+	ExecutionDate time.Time
+
+	// RecipientIDType is the type of RecipientIdentityNum. Defaults to the national ID number (TCKN).
+	// RecipientIdentityNum is optional for the easy address aliases, as they are resolved to their
+	// owners, but it is validated if it is set.
+	RecipientIDType RecipientIDType
+
+	// RecipientAddress is the destination address of the payment, e.g. an easy address alias
+	// created by PhoneAddress. RecipientIBAN must be empty if it is set.
+	RecipientAddress Address
+
+	// TransferMethod is the interbank transfer method of the payment. If it is empty, the bank selects it,
+	// unless AutoTransferMethod is set.
+	TransferMethod TrxTransferMethod
+
+	// AutoTransferMethod selects the transfer method if TransferMethod is empty: HAVALE for the recipients
+	// in the same bank, FAST for the amounts within the FASTLimit of the ClientOptions, and EFT otherwise.
+	// The banks are compared by the bank codes of the IBANs, whether or not the iban package knows them.
+	AutoTransferMethod bool
 }
 
 type PaymentResult struct {
 	PaymentID uuid.UUID `json:"payment_id"`
 }
 
+// IsTerminal reports whether the payment status is final.
+func (s PaymentStatus) IsTerminal() bool {
+	return s == PaymentStatusSent || s == PaymentStatusFailed || s == PaymentStatusRejected
+}
+
+type Payment struct {
+	ID            uuid.UUID       `json:"payment_id"`
+	Status        PaymentStatus   `json:"status"`
+	FailureReason string          `json:"failure_reason,omitempty"`
+	Amount        decimal.Decimal `json:"amount"`
+	RefCode       string          `json:"reference_code"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	ExecutedAt    *time.Time      `json:"executed_at,omitempty"`
+}
+
 type paymentAddr struct {
 	AddrType string `json:"addressType"`
 	Addr     string `json:"address"`
@@ -137,9 +204,9 @@ type paymentRecipientID struct {
 }
 
 type paymentDst struct {
-	Addr paymentAddr        `json:"address"`
-	ID   paymentRecipientID `json:"identifier"`
-	Name string             `json:"name"`
+	Addr paymentAddr         `json:"address"`
+	ID   *paymentRecipientID `json:"identifier,omitempty"`
+	Name string              `json:"name"`
 }
 
 type paymentReq struct {
@@ -150,4 +217,5 @@ type paymentReq struct {
 	RefCode  string      `json:"refNum"`
 	Desc     string      `json:"description"`
 	Callback string      `json:"callbackURL"`
+	Method   string      `json:"transferMethod,omitempty"`
 }
@@ -0,0 +1,433 @@
+package corpbankclient
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/birapi/go-corpbankclient/iban"
+	"github.com/birapi/go-corpbankclient/identity"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	paymentDateLayout = "2006-01-02T15:04:05.000Z"
+
+	// immediatePaymentDate is sent as the execution date of the payments to be executed immediately.
+	immediatePaymentDate = "1970-01-01T00:00:00.000Z"
+
+	// noCallbackURL is sent as the callback URL of the payments without any callback URL.
+	noCallbackURL = "http://example.com"
+
+	defaultPollInterval        = 5 * time.Second
+	defaultNotFoundGracePeriod = 30 * time.Second
+)
+
+// WaitOptions customizes the polling behavior of WaitForPayment.
+type WaitOptions struct {
+	// PollInterval is the wait duration between the checks. Defaults to 5 seconds.
+	PollInterval time.Duration
+
+	// NotFoundGracePeriod is the duration, since the start of the wait, while the payment is
+	// considered not yet visible if the remote service does not find it. Defaults to 30 seconds.
+	NotFoundGracePeriod time.Duration
+}
+
+// WaitForPayment polls the payment until it reaches a terminal state, or a bank transaction
+// with the payment ID appears in the list of transactions. The returned transaction is nil
+// if the payment fails, or it is sent but the transaction is not listed yet.
+func (c *Client) WaitForPayment(ctx context.Context, paymentID uuid.UUID, opts *WaitOptions) (*Payment, *Transaction, error) {
+	pollInterval := defaultPollInterval
+	if opts != nil && opts.PollInterval > 0 {
+		pollInterval = opts.PollInterval
+	}
+
+	notFoundGracePeriod := defaultNotFoundGracePeriod
+	if opts != nil && opts.NotFoundGracePeriod > 0 {
+		notFoundGracePeriod = opts.NotFoundGracePeriod
+	}
+
+	started := time.Now()
+
+	// scannedUntil is the end of the date range of the transactions already scanned
+	var scannedUntil time.Time
+
+	for {
+		payment, err := c.Payment(ctx, paymentID)
+
+		switch {
+		// the payments may not be visible right after they are created
+		case errors.Is(err, ErrNotFound) && time.Since(started) < notFoundGracePeriod:
+
+		case err != nil:
+			return nil, nil, errors.WithStack(err)
+
+		case payment.Status.IsTerminal() && payment.Status != PaymentStatusSent:
+			return payment, nil, nil
+
+		default:
+			trx, until, err := c.paymentTransaction(ctx, payment, scannedUntil)
+			if err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+
+			if trx != nil || payment.Status.IsTerminal() {
+				return payment, trx, nil
+			}
+
+			scannedUntil = until
+		}
+
+		if err := sleepCtx(ctx, pollInterval); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+}
+
+// paymentTransaction looks up the outgoing bank transaction of the payment, created after the payment.
+// Only the transactions after the end of the previous scan are listed, with an overlap of the maximum
+// time difference for the late transactions. It returns the end of the scanned date range.
+func (c *Client) paymentTransaction(ctx context.Context, payment *Payment, scannedUntil time.Time) (*Transaction, time.Time, error) {
+	startDate := payment.CreatedAt.Add(-c.maxTimeDiff)
+	if payment.CreatedAt.IsZero() {
+		startDate = time.Now().Add(-24 * time.Hour)
+	}
+
+	if since := scannedUntil.Add(-2 * c.maxTimeDiff); since.After(startDate) {
+		startDate = since
+	}
+
+	endDate := time.Now().Add(c.maxTimeDiff)
+
+	it := c.TransactionIterator(ctx,
+		WithFilterOutgoingTransactions(),
+		WithFilterInDateRange(startDate, endDate),
+	)
+
+	defer it.Close()
+
+	for it.Next() {
+		if trx := it.Transaction(); trx.PaymentID != nil && *trx.PaymentID == payment.ID {
+			return &trx, endDate, nil
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, scannedUntil, errors.WithStack(err)
+	}
+
+	return nil, endDate, nil
+}
+
+// paymentReq validates the payment order and builds the request payload.
+func (c *Client) paymentReq(o *PaymentOrder) (*paymentReq, error) {
+	callbackURL := o.CallbackURL
+	if callbackURL == "" {
+		callbackURL = c.callbackURL
+	}
+
+	if callbackURL == "" {
+		callbackURL = noCallbackURL
+	} else if err := validateCallbackURL(callbackURL); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	senderIBAN, err := validateIBAN("sender", o.SenderIBAN)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// the amounts are not rounded, so that the amount sent is the amount ordered
+	if !o.TransferAmount.IsPositive() {
+		return nil, errors.Wrapf(ErrInvalidPaymentOrder, "the amount must be positive: %s", o.TransferAmount)
+	}
+
+	if !o.TransferAmount.Equal(o.TransferAmount.Round(2)) {
+		return nil, errors.Wrapf(ErrInvalidPaymentOrder, "the amount can not have more than 2 decimal places: %s", o.TransferAmount)
+	}
+
+	dstAddr, err := recipientAddr(o)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	recipientID, err := recipientIdentifier(o, dstAddr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	date := immediatePaymentDate
+	if !o.ExecutionDate.IsZero() {
+		if err := validateExecutionDate(o.ExecutionDate, time.Now()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		date = o.ExecutionDate.UTC().Format(paymentDateLayout)
+	}
+
+	method, err := c.transferMethod(o, senderIBAN, dstAddr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := c.checkTransferHours(method, o.ExecutionDate, time.Now()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &paymentReq{
+		Src: paymentAddr{
+			AddrType: string(AddrTypeIBAN),
+			Addr:     senderIBAN,
+		},
+		Dst: paymentDst{
+			Addr: paymentAddr{
+				AddrType: string(dstAddr.Type),
+				Addr:     dstAddr.Value,
+			},
+			ID:   recipientID,
+			Name: o.RecipientName,
+		},
+		Date:     date,
+		Amount:   o.TransferAmount.StringFixed(2),
+		RefCode:  o.RefCode,
+		Desc:     o.Description,
+		Callback: callbackURL,
+		Method:   string(method),
+	}, nil
+}
+
+// transferMethod validates the transfer method of the payment order, or selects one if it is empty
+// and the automatic selection is enabled. Otherwise, it is left to the bank.
+func (c *Client) transferMethod(o *PaymentOrder, senderIBAN string, dst Address) (TrxTransferMethod, error) {
+	if o.TransferMethod == "" && !o.AutoTransferMethod {
+		return "", nil
+	}
+
+	withinFASTLimit := o.TransferAmount.LessThanOrEqual(c.fastLimit)
+
+	switch o.TransferMethod {
+	case "":
+		sameBank, err := isSameBank(senderIBAN, dst)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		switch {
+		case sameBank:
+			return TrxTransferMethodHavale, nil
+		case withinFASTLimit:
+			return TrxTransferMethodFAST, nil
+		case dst.Type != AddrTypeIBAN:
+			return "", errors.Wrapf(ErrInvalidPaymentOrder, "the amount exceeds the FAST limit (%s) of the easy address payments", c.fastLimit.StringFixed(2))
+		default:
+			return TrxTransferMethodEFT, nil
+		}
+
+	case TrxTransferMethodHavale:
+		sameBank, err := isSameBank(senderIBAN, dst)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		if !sameBank {
+			return "", errors.Wrap(ErrInvalidPaymentOrder, "HAVALE requires the recipient IBAN in the same bank")
+		}
+
+	case TrxTransferMethodFAST:
+		if !withinFASTLimit {
+			return "", errors.Wrapf(ErrInvalidPaymentOrder, "the amount exceeds the FAST limit: %s", c.fastLimit.StringFixed(2))
+		}
+
+	case TrxTransferMethodEFT:
+		if dst.Type != AddrTypeIBAN {
+			return "", errors.Wrap(ErrInvalidPaymentOrder, "EFT requires the recipient IBAN")
+		}
+
+	default:
+		return "", errors.Wrapf(ErrInvalidPaymentOrder, "unknown transfer method: `%s`", o.TransferMethod)
+	}
+
+	return o.TransferMethod, nil
+}
+
+// isSameBank reports whether the recipient IBAN is in the bank of the sender, by comparing the bank
+// codes of the IBANs. The banks missing from the bank list of the iban package are compared the same way.
+func isSameBank(senderIBAN string, dst Address) (bool, error) {
+	if dst.Type != AddrTypeIBAN {
+		return false, nil
+	}
+
+	var codes [2]string
+
+	for i, v := range []string{senderIBAN, dst.Value} {
+		code, err := iban.BankCode(v)
+		if err != nil {
+			return false, errors.Wrapf(ErrInvalidPaymentOrder, "unable to resolve the bank: %s", err.Error())
+		}
+
+		codes[i] = code
+	}
+
+	return codes[0] == codes[1], nil
+}
+
+// checkTransferHours rejects the EFT payments out of the EFT hours, if the client has a transfer calendar.
+// The scheduled payments are checked only against the business days. The days of the years unknown
+// to the calendar are rejected with transferhours.ErrUnknownYear.
+func (c *Client) checkTransferHours(method TrxTransferMethod, executionDate, now time.Time) error {
+	if c.calendar == nil || method != TrxTransferMethodEFT {
+		return nil
+	}
+
+	if !executionDate.IsZero() {
+		businessDay, err := c.calendar.IsBusinessDay(executionDate)
+		if err != nil {
+			return errors.Wrap(err, "unable to check the execution date")
+		}
+
+		if !businessDay {
+			return errors.Wrapf(ErrOutOfEFTHours, "the execution date is not a business day: %s", executionDate.Format("2006-01-02"))
+		}
+
+		return nil
+	}
+
+	open, err := c.calendar.IsEFTOpen(now)
+	if err != nil {
+		return errors.Wrap(err, "unable to check the EFT hours")
+	}
+
+	if open {
+		return nil
+	}
+
+	opensAt, err := c.calendar.NextEFTOpen(now)
+	if err != nil {
+		return errors.Wrap(err, "unable to find the next opening of the EFT system")
+	}
+
+	return errors.Wrapf(ErrOutOfEFTHours, "the EFT system opens at %s", opensAt.Format(time.RFC3339))
+}
+
+// recipientAddr returns the normalized destination address of the payment order.
+func recipientAddr(o *PaymentOrder) (Address, error) {
+	if o.RecipientAddress.IsZero() {
+		return IBANAddress(o.RecipientIBAN).Normalize()
+	}
+
+	if o.RecipientIBAN != "" {
+		return Address{}, errors.Wrap(ErrInvalidPaymentOrder, "both the recipient IBAN and the recipient address are set")
+	}
+
+	return o.RecipientAddress.Normalize()
+}
+
+// validateIBAN validates the IBAN and returns it in the electronic format.
+func validateIBAN(party, s string) (string, error) {
+	normalized := iban.Normalize(s)
+
+	if err := iban.Validate(normalized); err != nil {
+		return "", errors.Wrapf(ErrInvalidPaymentOrder, "invalid %s IBAN: %s", party, err.Error())
+	}
+
+	return normalized, nil
+}
+
+// recipientIdentifier returns the validated identifier of the recipient. It is required only for the
+// IBAN destinations, since the easy address aliases are resolved to their owners.
+func recipientIdentifier(o *PaymentOrder, dst Address) (*paymentRecipientID, error) {
+	if o.RecipientIdentityNum == "" && dst.Type != AddrTypeIBAN {
+		return nil, nil
+	}
+
+	idType := o.RecipientIDType
+	if idType == "" {
+		idType = RecipientIDTypeNationalID
+	}
+
+	if err := ValidateRecipientID(idType, o.RecipientIdentityNum); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &paymentRecipientID{IDType: string(idType), ID: o.RecipientIdentityNum}, nil
+}
+
+// ValidateRecipientID validates the recipient identification number according to its type,
+// by the checksums of the TCKN, YKN and VKN numbers. It returns ErrInvalidRecipientID if the
+// number is invalid, and ErrInvalidPaymentOrder if the type is unknown.
+func ValidateRecipientID(idType RecipientIDType, id string) error {
+	var err error
+
+	switch idType {
+	case RecipientIDTypeNationalID:
+		err = identity.ValidateTCKN(id)
+	case RecipientIDTypeTaxID:
+		err = identity.ValidateVKN(id)
+	case RecipientIDTypeForeignID:
+		err = identity.ValidateYKN(id)
+	case RecipientIDTypePassport:
+		err = identity.ValidatePassport(id)
+	default:
+		return errors.Wrapf(ErrInvalidPaymentOrder, "unknown recipient identifier type: `%s`", idType)
+	}
+
+	if err != nil {
+		return errors.Wrapf(ErrInvalidRecipientID, "invalid %s: %s", idType, err.Error())
+	}
+
+	return nil
+}
+
+// DetectRecipientIDType detects the type of the identification number by its format and checksum,
+// among the national ID (TCKN), foreign ID (YKN) and tax (VKN) numbers. The passport numbers can not
+// be detected. It returns ErrInvalidRecipientID if no type or more than one type matches the number.
+func DetectRecipientIDType(id string) (RecipientIDType, error) {
+	var matches []RecipientIDType
+
+	for _, idType := range []RecipientIDType{RecipientIDTypeNationalID, RecipientIDTypeForeignID, RecipientIDTypeTaxID} {
+		if ValidateRecipientID(idType, id) == nil {
+			matches = append(matches, idType)
+		}
+	}
+
+	// the foreign ID numbers are also valid national ID numbers, with a reserved prefix
+	if len(matches) == 2 && matches[0] == RecipientIDTypeNationalID && matches[1] == RecipientIDTypeForeignID {
+		matches = matches[1:]
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", errors.Wrapf(ErrInvalidRecipientID, "not a valid TCKN, YKN or VKN: `%s`", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.Wrapf(ErrInvalidRecipientID, "ambiguous identification number, one of %v: `%s`", matches, id)
+	}
+}
+
+func validateCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidPaymentOrder, "unable to parse the callback URL: `%s`", callbackURL)
+	}
+
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.Wrapf(ErrInvalidPaymentOrder, "the callback URL must be an absolute HTTP(S) URL: `%s`", callbackURL)
+	}
+
+	return nil
+}
+
+// validateExecutionDate checks that the execution date is not before the current day,
+// in the location of the execution date.
+func validateExecutionDate(date, now time.Time) error {
+	y, m, d := now.In(date.Location()).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+
+	if date.Before(today) {
+		return errors.Wrapf(ErrInvalidPaymentOrder, "the execution date is in the past: %s", date.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package corpbankclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/birapi/go-corpbankclient/transferhours"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+func TestDetectRecipientIDType(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    RecipientIDType
+		wantErr error
+	}{
+		{"10000000146", RecipientIDTypeNationalID, nil},
+		{"12345678950", RecipientIDTypeNationalID, nil},
+		{"99123456740", RecipientIDTypeForeignID, nil},
+		{"1234567890", RecipientIDTypeTaxID, nil},
+		{"9876543217", RecipientIDTypeTaxID, nil},
+		{"12345678951", "", ErrInvalidRecipientID},
+		{"99123456741", "", ErrInvalidRecipientID},
+		{"1234567891", "", ErrInvalidRecipientID},
+		{"U12345678", "", ErrInvalidRecipientID},
+		{"", "", ErrInvalidRecipientID},
+	}
+
+	for _, tt := range tests {
+		got, err := DetectRecipientIDType(tt.id)
+
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("DetectRecipientIDType(%q) error = %v, want %v", tt.id, err, tt.wantErr)
+		}
+
+		if got != tt.want {
+			t.Errorf("DetectRecipientIDType(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRecipientID(t *testing.T) {
+	tests := []struct {
+		idType  RecipientIDType
+		id      string
+		wantErr error
+	}{
+		{RecipientIDTypeNationalID, "10000000146", nil},
+		{RecipientIDTypeTaxID, "1234567890", nil},
+		{RecipientIDTypeForeignID, "99123456740", nil},
+		{RecipientIDTypePassport, "U12345678", nil},
+		{RecipientIDTypeNationalID, "1234567890", ErrInvalidRecipientID},
+		{RecipientIDTypeForeignID, "10000000146", ErrInvalidRecipientID},
+		{RecipientIDTypePassport, "u1", ErrInvalidRecipientID},
+		{"DRIVING_LICENSE", "10000000146", ErrInvalidPaymentOrder},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRecipientID(tt.idType, tt.id)
+
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("ValidateRecipientID(%s, %q) = %v, want %v", tt.idType, tt.id, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckTransferHours(t *testing.T) {
+	c := &Client{calendar: transferhours.New()}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr error
+	}{
+		{"open", time.Date(2024, time.January, 2, 10, 0, 0, 0, transferhours.Istanbul), nil},
+		{"closed", time.Date(2024, time.January, 2, 18, 0, 0, 0, transferhours.Istanbul), ErrOutOfEFTHours},
+		{"closed until a year unknown to the calendar", time.Date(2028, time.December, 29, 18, 0, 0, 0, transferhours.Istanbul), transferhours.ErrUnknownYear},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.checkTransferHours(TrxTransferMethodEFT, time.Time{}, tt.now)
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("checkTransferHours() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == transferhours.ErrUnknownYear && errors.Is(err, ErrOutOfEFTHours) {
+				t.Fatalf("checkTransferHours() = %v, want the calendar error only", err)
+			}
+		})
+	}
+}
+
+func TestTransferMethod(t *testing.T) {
+	c := &Client{fastLimit: defaultFASTLimit}
+
+	tests := []struct {
+		name      string
+		sender    string
+		recipient Address
+		method    TrxTransferMethod
+		amount    string
+		want      TrxTransferMethod
+		wantErr   bool
+	}{
+		{"same known bank", "TR400006200000000000000001", IBANAddress("TR400006200000000000000001"), "", "10", TrxTransferMethodHavale, false},
+		{"other bank", "TR400006200000000000000001", IBANAddress("TR420001000000000000000001"), "", "10", TrxTransferMethodFAST, false},
+		{"other bank over the FAST limit", "TR400006200000000000000001", IBANAddress("TR420001000000000000000001"), "", "100000.01", TrxTransferMethodEFT, false},
+		// the banks missing from the bank list are compared by their codes
+		{"same bank missing from the list", "TR330006100519786457841326", IBANAddress("TR690006100000000000000002"), "", "10", TrxTransferMethodHavale, false},
+		{"same unknown bank code", "TR450099900000000000000001", IBANAddress("TR180099900000000000000002"), "", "10", TrxTransferMethodHavale, false},
+		{"unknown bank code and a known bank", "TR450099900000000000000001", IBANAddress("TR420001000000000000000001"), "", "10", TrxTransferMethodFAST, false},
+		{"HAVALE to the same unknown bank code", "TR450099900000000000000001", IBANAddress("TR180099900000000000000002"), TrxTransferMethodHavale, "10", TrxTransferMethodHavale, false},
+		{"HAVALE to another bank", "TR450099900000000000000001", IBANAddress("TR420001000000000000000001"), TrxTransferMethodHavale, "10", "", true},
+		{"foreign IBAN", "TR400006200000000000000001", IBANAddress("GB82WEST12345698765432"), "", "10", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &PaymentOrder{
+				TransferMethod:     tt.method,
+				AutoTransferMethod: true,
+				TransferAmount:     decimal.RequireFromString(tt.amount),
+			}
+
+			got, err := c.transferMethod(o, tt.sender, tt.recipient)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPaymentOrder) {
+					t.Fatalf("transferMethod() = %v, want ErrInvalidPaymentOrder", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("transferMethod() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
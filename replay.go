@@ -0,0 +1,78 @@
+package corpbankclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplayStore remembers the processed webhook notifications, so that the replayed or
+// redelivered notifications are not processed more than once.
+type ReplayStore interface {
+	// Add records the key for the given TTL. It returns false if the key is already recorded.
+	// It must be atomic, since the same notification can be delivered concurrently.
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Remove forgets the key, so that the notification can be processed again.
+	Remove(ctx context.Context, key string) error
+}
+
+const (
+	defaultReplayTTL = 24 * time.Hour
+
+	// webhookLockTTL bounds the processing time of a webhook event, after which the lock expires
+	// in case the process is terminated while the event is in progress.
+	webhookLockTTL = 10 * time.Minute
+
+	replaySweepInterval = time.Minute
+)
+
+// MemoryReplayStore is an in-memory ReplayStore with expiring keys. It is suitable for
+// a single instance, multiple instances should share a ReplayStore backed by a database.
+type MemoryReplayStore struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryReplayStore returns an empty in-memory ReplayStore.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{
+		entries:   map[string]time.Time{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryReplayStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(s.lastSweep) > replaySweepInterval {
+		for k, exp := range s.entries {
+			if !exp.After(now) {
+				delete(s.entries, k)
+			}
+		}
+
+		s.lastSweep = now
+	}
+
+	if exp, ok := s.entries[key]; ok && exp.After(now) {
+		return false, nil
+	}
+
+	s.entries[key] = now.Add(ttl)
+
+	return true, nil
+}
+
+func (s *MemoryReplayStore) Remove(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
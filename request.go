@@ -164,29 +164,12 @@ func (c *Client) Transactions(ctx context.Context, options ...RequestOption) (*P
 
 // MakePayment sends payment order to the bank and returns the bank response.
 func (c *Client) MakePayment(ctx context.Context, paymentOrder PaymentOrder) (*PaymentResult, error) {
-	reqBody, err := json.Marshal(&paymentReq{
-		Src: paymentAddr{
-			AddrType: "IBAN",
-			Addr:     paymentOrder.SenderIBAN,
-		},
-		Dst: paymentDst{
-			Addr: paymentAddr{
-				AddrType: "IBAN",
-				Addr:     paymentOrder.RecipientIBAN,
-			},
-			ID: paymentRecipientID{
-				IDType: "NATIONAL_ID",
-				ID:     paymentOrder.RecipientIdentityNum,
-			},
-			Name: paymentOrder.RecipientName,
-		},
-		Date:     "1970-01-01T00:00:00.000Z",
-		Amount:   paymentOrder.TransferAmount.StringFixed(2),
-		RefCode:  paymentOrder.RefCode,
-		Desc:     paymentOrder.Description,
-		Callback: "http://example.com",
-	})
+	pReq, err := c.paymentReq(&paymentOrder)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 
+	reqBody, err := json.Marshal(pReq)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -199,7 +182,7 @@ func (c *Client) MakePayment(ctx context.Context, paymentOrder PaymentOrder) (*P
 	req.Header.Set("Content-Type", "application/json")
 
 	if ik := paymentOrder.IdempotencyKey; ik != "" {
-		req.Header.Set("X-Idempotency-Key", ik)
+		req.Header.Set(idempotencyKeyHeader, ik)
 	}
 
 	paymentResult := &PaymentResult{}
@@ -210,3 +193,18 @@ func (c *Client) MakePayment(ctx context.Context, paymentOrder PaymentOrder) (*P
 
 	return paymentResult, nil
 }
+
+// Payment returns the payment details and its current status by the given payment ID.
+func (c *Client) Payment(ctx context.Context, paymentID uuid.UUID) (*Payment, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.path("payments", paymentID.String()), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	respData := &Payment{}
+	if err := c.do(respData, req, http.StatusOK); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return respData, nil
+}
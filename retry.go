@@ -0,0 +1,162 @@
+package corpbankclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy describes how the client retries the failed requests. Transport errors
+// and the responses with 429 or 5xx status codes are retried with an exponential backoff.
+// The requests with non-idempotent methods (e.g. MakePayment) are retried only when
+// an idempotency key is set.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// InitialInterval is the wait duration before the first retry.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the wait duration between the attempts, including the value of
+	// the `Retry-After` response header.
+	MaxInterval time.Duration
+
+	// Multiplier is the factor to increase the wait duration after each attempt.
+	Multiplier float64
+
+	// Jitter randomizes the wait duration by the given factor, between 0 and 1.
+	Jitter float64
+}
+
+const (
+	defaultRetryMaxAttempts     = 4
+	defaultRetryInitialInterval = 200 * time.Millisecond
+	defaultRetryMaxInterval     = 30 * time.Second
+	defaultRetryMultiplier      = 2
+	defaultRetryJitter          = 0.5
+
+	idempotencyKeyHeader = "X-Idempotency-Key"
+)
+
+// DefaultRetryPolicy returns a retry policy with reasonable defaults.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:     defaultRetryMaxAttempts,
+		InitialInterval: defaultRetryInitialInterval,
+		MaxInterval:     defaultRetryMaxInterval,
+		Multiplier:      defaultRetryMultiplier,
+		Jitter:          defaultRetryJitter,
+	}
+}
+
+var (
+	jitterRandMu sync.Mutex
+	jitterRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns the wait duration before the given attempt (starting from 2).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	interval := float64(p.InitialInterval) * math.Pow(p.multiplier(), float64(attempt-2))
+
+	if max := float64(p.MaxInterval); max > 0 && interval > max {
+		interval = max
+	}
+
+	if p.Jitter > 0 {
+		jitterRandMu.Lock()
+		r := jitterRand.Float64()
+		jitterRandMu.Unlock()
+
+		delta := p.Jitter * interval
+		interval = interval - delta + r*2*delta
+	}
+
+	return time.Duration(interval)
+}
+
+// capInterval caps the wait duration requested by the remote service with MaxInterval.
+// It is safe to call on a nil policy.
+func (p *RetryPolicy) capInterval(d time.Duration, ok bool) time.Duration {
+	if !ok {
+		return 0
+	}
+
+	if p != nil && p.MaxInterval > 0 && d > p.MaxInterval {
+		return p.MaxInterval
+	}
+
+	return d
+}
+
+func (p *RetryPolicy) multiplier() float64 {
+	if p.Multiplier < 1 {
+		return 1
+	}
+
+	return p.Multiplier
+}
+
+// canRetry reports whether the request is safe to be sent again.
+func canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get(idempotencyKeyHeader) != ""
+}
+
+// retryAfter parses the `Retry-After` header, which can be either in seconds or an HTTP date.
+func retryAfter(hdr http.Header) (time.Duration, bool) {
+	v := hdr.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+
+		return d, true
+	}
+
+	return 0, false
+}
+
+// retryableStatus reports whether the responses with the status code are worth retrying.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
+
+func isPermanentErr(err error) bool {
+	for _, e := range permanentErrs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,118 @@
+package corpbankclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const defaultRotationGracePeriod = 15 * time.Minute
+
+// KeyRotator rotates the API key of a client. It creates a new API key, switches the client
+// to the new one, and keeps the previous key valid for a grace period, so that the in-flight
+// requests and webhook notifications signed by the previous key are still accepted.
+// Then, it disables and deletes the previous key.
+type KeyRotator struct {
+	Client *Client
+
+	// GracePeriod is the duration to keep the previous API key valid. Defaults to 15 minutes.
+	GracePeriod time.Duration
+
+	// OnKeyCreated is called with the credentials of the new API key, before the client switches
+	// to it. It is the place to persist the new secret. The rotation is aborted and the new
+	// API key is deleted if it returns an error.
+	OnKeyCreated func(ctx context.Context, apiCreds Credentials) error
+
+	// OnKeyActivated is called after the client switches to the new API key.
+	OnKeyActivated func(ctx context.Context, newKeyID, oldKeyID uuid.UUID)
+
+	// OnKeyRetired is called after the previous API key is deleted.
+	OnKeyRetired func(ctx context.Context, apiKeyID uuid.UUID)
+}
+
+// NewKeyRotator returns a key rotator for the client with the default grace period.
+func NewKeyRotator(client *Client) *KeyRotator {
+	return &KeyRotator{
+		Client:      client,
+		GracePeriod: defaultRotationGracePeriod,
+	}
+}
+
+// Rotate rotates the API key of the client and returns the new API key. It blocks during the
+// grace period. If the context is canceled during the grace period, the client keeps using
+// the new API key, and the previous one should be retired later by RetireKey.
+func (r *KeyRotator) Rotate(ctx context.Context) (*APIKey, error) {
+	oldKeyID := r.Client.KeyID()
+
+	newKey, err := r.Client.NewAPIKey(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create a new API key")
+	}
+
+	if newKey.Secret == nil {
+		return nil, errors.Errorf("the secret of the new API key is missing: %s", newKey.ID)
+	}
+
+	apiCreds := Credentials{
+		APIKeyID:     newKey.ID.String(),
+		APIKeySecret: *newKey.Secret,
+	}
+
+	if r.OnKeyCreated != nil {
+		if err := r.OnKeyCreated(ctx, apiCreds); err != nil {
+			if delErr := r.Client.DelAPIKey(ctx, newKey.ID); delErr != nil {
+				return nil, errors.Wrapf(err, "unable to delete the new API key %s (%s) after the hook error", newKey.ID, delErr.Error())
+			}
+
+			return nil, errors.Wrap(err, "rotation is aborted by the hook")
+		}
+	}
+
+	if err := r.Client.SetCredentials(apiCreds); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if r.OnKeyActivated != nil {
+		r.OnKeyActivated(ctx, newKey.ID, oldKeyID)
+	}
+
+	gracePeriod := r.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultRotationGracePeriod
+	}
+
+	if err := sleepCtx(ctx, gracePeriod); err != nil {
+		return newKey, errors.Wrapf(err, "the previous API key is not retired: %s", oldKeyID)
+	}
+
+	if err := r.RetireKey(ctx, oldKeyID); err != nil {
+		return newKey, errors.WithStack(err)
+	}
+
+	return newKey, nil
+}
+
+// RetireKey disables and deletes the API key, and removes it from the keyring of the webhook verifier.
+func (r *KeyRotator) RetireKey(ctx context.Context, apiKeyID uuid.UUID) error {
+	if apiKeyID == r.Client.KeyID() {
+		return errors.Errorf("the API key is still in use by the client: %s", apiKeyID)
+	}
+
+	if err := r.Client.DisableAPIKey(ctx, apiKeyID); err != nil {
+		return errors.Wrapf(err, "unable to disable the API key: %s", apiKeyID)
+	}
+
+	if err := r.Client.DelAPIKey(ctx, apiKeyID); err != nil {
+		return errors.Wrapf(err, "unable to delete the API key: %s", apiKeyID)
+	}
+
+	r.Client.WebhookVerifier().RemoveKey(apiKeyID)
+
+	if r.OnKeyRetired != nil {
+		r.OnKeyRetired(ctx, apiKeyID)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package corpbankclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Signer computes the HMAC-SHA256 signatures of the bearer tokens. It allows keeping the
+// API key secret out of the process memory, e.g. in an HSM or a KMS.
+type Signer interface {
+	// Sign returns the HMAC-SHA256 of the message streamed from the reader.
+	Sign(message io.Reader) ([]byte, error)
+}
+
+type hmacSigner []byte
+
+// NewHMACSigner returns a signer holding the API key secret in memory.
+func NewHMACSigner(apiKeySecret []byte) Signer {
+	return hmacSigner(apiKeySecret)
+}
+
+func (s hmacSigner) Sign(message io.Reader) ([]byte, error) {
+	h := hmac.New(sha256.New, s)
+
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, errors.Wrap(err, "unable to read the message to sign")
+	}
+
+	return h.Sum(nil), nil
+}
+
+// FileSigner reads the base64 encoded API key secret from a file for each signature,
+// instead of keeping it in memory. It is a reference implementation to test the Signer
+// integrations, and it is not meant to be a secure storage.
+type FileSigner struct {
+	Path string
+}
+
+func (s *FileSigner) Sign(message io.Reader) ([]byte, error) {
+	content, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the API secret file: `%s`", s.Path)
+	}
+
+	apiKeySec, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the API secret file: `%s`", s.Path)
+	}
+
+	return hmacSigner(apiKeySec).Sign(message)
+}
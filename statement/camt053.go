@@ -0,0 +1,277 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+const (
+	camtDateLayout     = "2006-01-02"
+	camtDateTimeLayout = "2006-01-02T15:04:05-07:00"
+)
+
+// Balance types and indicators of the camt.053 messages.
+const (
+	camtOpeningBooked = "OPBD"
+	camtClosingBooked = "CLBD"
+	camtCredit        = "CRDT"
+	camtDebit         = "DBIT"
+	camtBooked        = "BOOK"
+)
+
+// camtNoTransactionCode is the bank transaction code of the transactions without a transfer method,
+// since the code is mandatory.
+const camtNoTransactionCode = "NTRF"
+
+type camt053Document struct {
+	XMLName xml.Name `xml:"Document"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Stmt    struct {
+		GrpHdr struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+
+		Stmt struct {
+			ID           string `xml:"Id"`
+			ElctrncSeqNb int    `xml:"ElctrncSeqNb"`
+			CreDtTm      string `xml:"CreDtTm"`
+			FrDtTm       string `xml:"FrToDt>FrDtTm"`
+			ToDtTm       string `xml:"FrToDt>ToDtTm"`
+
+			Acct struct {
+				IBAN string `xml:"Id>IBAN"`
+				Ccy  string `xml:"Ccy"`
+				Nm   string `xml:"Ownr>Nm,omitempty"`
+			} `xml:"Acct"`
+
+			Bals []camtBalance `xml:"Bal"`
+
+			TxsSummry struct {
+				TtlNtries struct {
+					NbOfNtries    string `xml:"NbOfNtries"`
+					Sum           string `xml:"Sum"`
+					TtlNetNtryAmt string `xml:"TtlNetNtryAmt"`
+					CdtDbtInd     string `xml:"CdtDbtInd"`
+				} `xml:"TtlNtries"`
+
+				TtlCdtNtries camtNumberAndSum `xml:"TtlCdtNtries"`
+				TtlDbtNtries camtNumberAndSum `xml:"TtlDbtNtries"`
+			} `xml:"TxsSummry"`
+
+			Ntries []camtEntry `xml:"Ntry"`
+		} `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camtAmount struct {
+	Value string `xml:",chardata"`
+	Ccy   string `xml:"Ccy,attr"`
+}
+
+type camtBalance struct {
+	Tp        string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        string     `xml:"Dt>Dt"`
+}
+
+type camtNumberAndSum struct {
+	NbOfNtries string `xml:"NbOfNtries"`
+	Sum        string `xml:"Sum"`
+}
+
+type camtEntry struct {
+	Amt         camtAmount `xml:"Amt"`
+	CdtDbtInd   string     `xml:"CdtDbtInd"`
+	Sts         string     `xml:"Sts"`
+	BookgDt     string     `xml:"BookgDt>Dt"`
+	ValDt       string     `xml:"ValDt>Dt"`
+	AcctSvcrRef string     `xml:"AcctSvcrRef"`
+	BkTxCd      string     `xml:"BkTxCd>Prtry>Cd"`
+
+	TxDtls struct {
+		Refs *struct {
+			EndToEndID string `xml:"EndToEndId"`
+		} `xml:"Refs,omitempty"`
+
+		RltdPties *camtRelatedParties `xml:"RltdPties,omitempty"`
+
+		// the remittance information is omitted with its element, if there is no description
+		RmtInf *struct {
+			Ustrd string `xml:"Ustrd"`
+		} `xml:"RmtInf,omitempty"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtRelatedParties struct {
+	Dbtr     *camtParty   `xml:"Dbtr,omitempty"`
+	DbtrAcct *camtAccount `xml:"DbtrAcct,omitempty"`
+	Cdtr     *camtParty   `xml:"Cdtr,omitempty"`
+	CdtrAcct *camtAccount `xml:"CdtrAcct,omitempty"`
+}
+
+type camtParty struct {
+	Nm string `xml:"Nm"`
+}
+
+type camtAccount struct {
+	IBAN string `xml:"Id>IBAN"`
+}
+
+// WriteCamt053 writes the statement as an ISO 20022 camt.053.001.02 bank to customer statement.
+// The transactions are reported as booked entries, with the transaction IDs as the account
+// servicer references, and the reference codes as the end-to-end IDs.
+func WriteCamt053(w io.Writer, st *Statement) error {
+	loc := st.location()
+
+	doc := &camt053Document{Xmlns: camt053Namespace}
+
+	doc.Stmt.GrpHdr.MsgID = strings.ReplaceAll(uuid.New().String(), "-", "")
+	doc.Stmt.GrpHdr.CreDtTm = st.CreatedAt.In(loc).Format(camtDateTimeLayout)
+
+	s := &doc.Stmt.Stmt
+
+	s.ID = st.ID
+	s.ElctrncSeqNb = st.SequenceNumber
+	s.CreDtTm = doc.Stmt.GrpHdr.CreDtTm
+	s.FrDtTm = st.From.In(loc).Format(camtDateTimeLayout)
+	s.ToDtTm = st.To.In(loc).Format(camtDateTimeLayout)
+
+	s.Acct.IBAN = st.Account.IBAN
+	s.Acct.Ccy = st.Account.Currency
+	s.Acct.Nm = st.Account.Owner
+
+	s.Bals = []camtBalance{
+		st.camtBalance(camtOpeningBooked, st.OpeningBalance, st.From.In(loc).Format(camtDateLayout)),
+		st.camtBalance(camtClosingBooked, st.ClosingBalance, st.lastDay().Format(camtDateLayout)),
+	}
+
+	credits, debits, creditSum, debitSum := st.totals()
+
+	ttl := &s.TxsSummry.TtlNtries
+	ttl.NbOfNtries = strconv.Itoa(credits + debits)
+	ttl.Sum = camtDecimal(creditSum.Add(debitSum))
+	ttl.TtlNetNtryAmt = camtDecimal(creditSum.Sub(debitSum).Abs())
+	ttl.CdtDbtInd = camtIndicator(creditSum.Sub(debitSum))
+
+	s.TxsSummry.TtlCdtNtries = camtNumberAndSum{strconv.Itoa(credits), camtDecimal(creditSum)}
+	s.TxsSummry.TtlDbtNtries = camtNumberAndSum{strconv.Itoa(debits), camtDecimal(debitSum)}
+
+	for _, trx := range st.Transactions {
+		s.Ntries = append(s.Ntries, st.camtEntry(trx))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.WithStack(err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return errors.Wrap(err, "unable to write the camt.053 message")
+	}
+
+	return nil
+}
+
+func (st *Statement) camtBalance(tp string, amount decimal.Decimal, date string) camtBalance {
+	return camtBalance{
+		Tp:        tp,
+		Amt:       camtAmount{Value: camtDecimal(amount.Abs()), Ccy: st.Account.Currency},
+		CdtDbtInd: camtIndicator(amount),
+		Dt:        date,
+	}
+}
+
+func (st *Statement) camtEntry(trx corpbankclient.Transaction) camtEntry {
+	date := trx.Date.In(st.location()).Format(camtDateLayout)
+
+	e := camtEntry{
+		Amt:         camtAmount{Value: camtDecimal(trx.Amount.Abs()), Ccy: st.Account.Currency},
+		CdtDbtInd:   camtIndicator(signedAmount(trx)),
+		Sts:         camtBooked,
+		BookgDt:     date,
+		ValDt:       date,
+		AcctSvcrRef: trx.ID.String(),
+		BkTxCd:      camtTransactionCode(trx),
+	}
+
+	if trx.RefCode != "" {
+		e.TxDtls.Refs = &struct {
+			EndToEndID string `xml:"EndToEndId"`
+		}{trx.RefCode}
+	}
+
+	if d := strings.TrimSpace(trx.Description); d != "" {
+		e.TxDtls.RmtInf = &struct {
+			Ustrd string `xml:"Ustrd"`
+		}{d}
+	}
+
+	if trx.Sender == nil && trx.Recipient == nil {
+		return e
+	}
+
+	e.TxDtls.RltdPties = &camtRelatedParties{}
+
+	if p := trx.Sender; p != nil {
+		e.TxDtls.RltdPties.Dbtr, e.TxDtls.RltdPties.DbtrAcct = camtPartyOf(p)
+	}
+
+	if p := trx.Recipient; p != nil {
+		e.TxDtls.RltdPties.Cdtr, e.TxDtls.RltdPties.CdtrAcct = camtPartyOf(p)
+	}
+
+	return e
+}
+
+func camtPartyOf(p *corpbankclient.TransactionParticipant) (*camtParty, *camtAccount) {
+	var (
+		party *camtParty
+		acct  *camtAccount
+	)
+
+	if p.Name != "" {
+		party = &camtParty{Nm: p.Name}
+	}
+
+	if p.IBAN != "" {
+		acct = &camtAccount{IBAN: p.IBAN}
+	}
+
+	return party, acct
+}
+
+// camtTransactionCode returns the transfer method of the transaction as the proprietary bank
+// transaction code.
+func camtTransactionCode(trx corpbankclient.Transaction) string {
+	if trx.TransferMethod == "" {
+		return camtNoTransactionCode
+	}
+
+	return string(trx.TransferMethod)
+}
+
+// camtIndicator returns the credit indicator of the zero amounts, as the balances can not be signed.
+func camtIndicator(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return camtDebit
+	}
+
+	return camtCredit
+}
+
+func camtDecimal(amount decimal.Decimal) string {
+	return amount.StringFixed(2)
+}
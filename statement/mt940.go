@@ -0,0 +1,204 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	mt940DateLayout      = "060102"
+	mt940EntryDateLayout = "0102"
+)
+
+// Field lengths of the MT940 messages.
+const (
+	mt940MaxRefLen       = 16
+	mt940MaxInfoLines    = 6
+	mt940MaxInfoLineLen  = 65
+	mt940MaxSupplDetails = 34
+)
+
+// mt940NoRef is the reference of the transactions without a reference code.
+const mt940NoRef = "NONREF"
+
+// mt940TransactionType is the transaction type of the non-SWIFT transfers.
+const mt940TransactionType = "NTRF"
+
+// transliteration maps the Turkish letters onto the SWIFT X character set.
+var transliteration = strings.NewReplacer(
+	"ç", "c", "Ç", "C",
+	"ğ", "g", "Ğ", "G",
+	"ı", "i", "İ", "I",
+	"ö", "o", "Ö", "O",
+	"ş", "s", "Ş", "S",
+	"ü", "u", "Ü", "U",
+	"â", "a", "Â", "A",
+	"î", "i", "Î", "I",
+	"û", "u", "Û", "U",
+)
+
+// WriteMT940 writes the statement as a SWIFT MT940 customer statement message, without the
+// headers of the SWIFT network. The texts are transliterated into the SWIFT X character set.
+func WriteMT940(w io.Writer, st *Statement) error {
+	loc := st.location()
+	bw := bufio.NewWriter(w)
+
+	line := func(format string, a ...interface{}) {
+		fmt.Fprintf(bw, format+"\r\n", a...)
+	}
+
+	line(":20:%s", mt940Ref(st.ID))
+	line(":25:%s", st.Account.IBAN)
+	line(":28C:%d/1", st.SequenceNumber)
+	line(":60F:%s", mt940Balance(st.OpeningBalance, st.From.In(loc), st.Account.Currency))
+
+	for _, trx := range st.Transactions {
+		date := trx.Date.In(loc)
+
+		ref := mt940Ref(trx.RefCode)
+		if strings.TrimSpace(ref) == "" {
+			ref = mt940NoRef
+		}
+
+		line(":61:%s%s%s%s%s%s//%s",
+			date.Format(mt940DateLayout),
+			date.Format(mt940EntryDateLayout),
+			mt940Indicator(signedAmount(trx)),
+			mt940Decimal(trx.Amount.Abs()),
+			mt940TransactionType,
+			ref,
+			truncate(strings.ReplaceAll(trx.ID.String(), "-", ""), mt940MaxRefLen),
+		)
+
+		if p := counterparty(trx); p != nil && swiftText(p.Name) != "" {
+			line("%s", swiftLine(truncate(swiftText(p.Name), mt940MaxSupplDetails)))
+		}
+
+		if info := mt940Info(trx); len(info) > 0 {
+			line(":86:%s", strings.Join(info, "\r\n"))
+		}
+	}
+
+	line(":62F:%s", mt940Balance(st.ClosingBalance, st.lastDay(), st.Account.Currency))
+	line("-")
+
+	return errors.WithStack(bw.Flush())
+}
+
+// mt940Balance formats the balance fields, e.g. "C240131TRY1234,56".
+func mt940Balance(amount decimal.Decimal, date time.Time, currency string) string {
+	return mt940Indicator(amount) + date.Format(mt940DateLayout) + currency + mt940Decimal(amount.Abs())
+}
+
+func mt940Indicator(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return "D"
+	}
+
+	return "C"
+}
+
+// mt940Decimal formats the amount with a decimal comma, e.g. "1234,56".
+func mt940Decimal(amount decimal.Decimal) string {
+	return strings.Replace(amount.StringFixed(2), ".", ",", 1)
+}
+
+// counterparty returns the other party of the transaction, or nil if it is unknown.
+func counterparty(trx corpbankclient.Transaction) *corpbankclient.TransactionParticipant {
+	if trx.Direction == corpbankclient.TrxDirectionOutgoing {
+		return trx.Recipient
+	}
+
+	return trx.Sender
+}
+
+// mt940Info returns the lines of the information to account owner field, with the description,
+// the transfer method, and the name and IBAN of the other party of the transaction.
+func mt940Info(trx corpbankclient.Transaction) []string {
+	var parts []string
+
+	if trx.TransferMethod != "" {
+		parts = append(parts, string(trx.TransferMethod))
+	}
+
+	if d := strings.TrimSpace(trx.Description); d != "" {
+		parts = append(parts, d)
+	}
+
+	if p := counterparty(trx); p != nil {
+		if p.Name != "" {
+			parts = append(parts, p.Name)
+		}
+
+		if p.IBAN != "" {
+			parts = append(parts, p.IBAN)
+		}
+	}
+
+	text := []rune(swiftText(strings.Join(parts, " ")))
+
+	var lines []string
+
+	for len(text) > 0 && len(lines) < mt940MaxInfoLines {
+		n := mt940MaxInfoLineLen
+		if len(text) < n {
+			n = len(text)
+		}
+
+		lines = append(lines, swiftLine(string(text[:n])))
+		text = text[n:]
+	}
+
+	return lines
+}
+
+// swiftText transliterates the text into the SWIFT X character set, replacing the other
+// characters with spaces.
+func swiftText(s string) string {
+	s = transliteration.Replace(s)
+
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("/-?:().,'+ ", r):
+			return r
+		default:
+			return ' '
+		}
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// mt940Ref formats the reference fields in the SWIFT X character set. The slashes are replaced with
+// hyphens, since the "//" separates the reference of the account owner from the reference of the bank.
+func mt940Ref(s string) string {
+	return truncate(strings.ReplaceAll(swiftText(s), "/", "-"), mt940MaxRefLen)
+}
+
+// swiftLine prevents the continuation lines from starting with a colon or a hyphen, which would
+// be taken as the beginning of a field or the end of the message.
+func swiftLine(s string) string {
+	if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "-") {
+		return "." + s[1:]
+	}
+
+	return s
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
@@ -0,0 +1,209 @@
+// Package statement exports the bank transactions of an account as the bank statements
+// in the ISO 20022 camt.053 and SWIFT MT940 formats.
+package statement
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/birapi/go-corpbankclient/transferhours"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const defaultCurrency = "TRY"
+
+// idDateLayout is the date layout of the default statement IDs, e.g. "240101-240131".
+const idDateLayout = "060102"
+
+// Account is the account of the statement.
+type Account struct {
+	IBAN string
+
+	// Currency is the ISO 4217 currency code of the account. Defaults to TRY.
+	Currency string
+
+	// Owner is the name of the account owner, it is optional.
+	Owner string
+}
+
+// Statement is the list of the transactions of an account within a period, between the opening
+// and closing balances. The period includes From, and excludes To.
+type Statement struct {
+	// ID identifies the statement, it is truncated to 16 characters in MT940. Defaults to the first
+	// and last days of the period.
+	ID string
+
+	// SequenceNumber is the sequence number of the statement. Defaults to 1.
+	SequenceNumber int
+
+	Account Account
+	From    time.Time
+	To      time.Time
+
+	OpeningBalance decimal.Decimal
+	ClosingBalance decimal.Decimal
+
+	// Transactions are sorted by their dates.
+	Transactions []corpbankclient.Transaction
+
+	CreatedAt time.Time
+
+	// Location is the time zone of the dates. Defaults to Istanbul.
+	Location *time.Location
+}
+
+// New returns the statement of the account within the period. The balances are derived from the
+// account balance, by adding the incoming and subtracting the outgoing transaction amounts,
+// so the transactions must cover the period from the beginning of the statement to the last
+// update of the balance. The transactions of the other accounts are ignored.
+func New(account Account, from, to time.Time, balance *corpbankclient.AccountBalance, transactions []corpbankclient.Transaction) (*Statement, error) {
+	if !from.Before(to) {
+		return nil, errors.Errorf("invalid statement period: %s - %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	if account.Currency == "" {
+		account.Currency = defaultCurrency
+	}
+
+	st := &Statement{
+		SequenceNumber: 1,
+		Account:        account,
+		From:           from,
+		To:             to,
+		CreatedAt:      time.Now(),
+		Location:       transferhours.Istanbul,
+	}
+
+	st.ID = from.In(st.Location).Format(idDateLayout) + "-" + st.lastDay().Format(idDateLayout)
+
+	var accountTrxs []corpbankclient.Transaction
+
+	for _, trx := range transactions {
+		if account.IBAN != "" && trx.Account.IBAN != "" && trx.Account.IBAN != account.IBAN {
+			continue
+		}
+
+		if trx.Currency != "" && trx.Currency != account.Currency {
+			return nil, errors.Errorf("the currency of the transaction %s does not match the account: `%s`", trx.ID, trx.Currency)
+		}
+
+		if trx.Direction != corpbankclient.TrxDirectionIncoming && trx.Direction != corpbankclient.TrxDirectionOutgoing {
+			return nil, errors.Errorf("unknown direction of the transaction %s: `%s`", trx.ID, trx.Direction)
+		}
+
+		accountTrxs = append(accountTrxs, trx)
+
+		if !trx.Date.Before(from) && trx.Date.Before(to) {
+			st.Transactions = append(st.Transactions, trx)
+		}
+	}
+
+	sort.SliceStable(st.Transactions, func(i, j int) bool {
+		return st.Transactions[i].Date.Before(st.Transactions[j].Date)
+	})
+
+	st.OpeningBalance = balanceAt(balance, accountTrxs, from)
+	st.ClosingBalance = balanceAt(balance, accountTrxs, to)
+
+	return st, nil
+}
+
+// Fetch reads the account balance and the transactions of the account from the remote service,
+// and returns the statement of the account within the period.
+func Fetch(ctx context.Context, client *corpbankclient.Client, accountID uuid.UUID, account Account, from, to time.Time) (*Statement, error) {
+	balance, err := client.AccountBalance(ctx, accountID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// the transactions between the period and the last update of the balance are needed to derive
+	// the balances, whether it is updated before or after the period
+	start, end := from, to
+	if balance.LastUpdatedAt.Before(start) {
+		start = balance.LastUpdatedAt
+	}
+
+	if balance.LastUpdatedAt.After(end) {
+		end = balance.LastUpdatedAt
+	}
+
+	// the range is widened, since the filter drops the fractional seconds, and the transactions out of
+	// the period are ignored by their exact dates
+	it := client.TransactionIterator(ctx,
+		corpbankclient.WithFilterAccountIDs(accountID),
+		corpbankclient.WithFilterInDateRange(start.Add(-time.Second), end.Add(time.Second)),
+	)
+
+	defer it.Close()
+
+	var transactions []corpbankclient.Transaction
+
+	for it.Next() {
+		transactions = append(transactions, it.Transaction())
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return New(account, from, to, balance, transactions)
+}
+
+// balanceAt derives the balance at t, from the balance at its last update.
+func balanceAt(balance *corpbankclient.AccountBalance, transactions []corpbankclient.Transaction, t time.Time) decimal.Decimal {
+	b := balance.Balance
+
+	for _, trx := range transactions {
+		switch {
+		// the transactions between t and the last update are reverted
+		case !trx.Date.Before(t) && !trx.Date.After(balance.LastUpdatedAt):
+			b = b.Sub(signedAmount(trx))
+
+		// the transactions between the last update and t are applied
+		case trx.Date.After(balance.LastUpdatedAt) && trx.Date.Before(t):
+			b = b.Add(signedAmount(trx))
+		}
+	}
+
+	return b
+}
+
+func signedAmount(trx corpbankclient.Transaction) decimal.Decimal {
+	if trx.Direction == corpbankclient.TrxDirectionOutgoing {
+		return trx.Amount.Abs().Neg()
+	}
+
+	return trx.Amount.Abs()
+}
+
+// totals returns the number and the sum of the credit and debit entries.
+func (st *Statement) totals() (credits, debits int, creditSum, debitSum decimal.Decimal) {
+	for _, trx := range st.Transactions {
+		if trx.Direction == corpbankclient.TrxDirectionOutgoing {
+			debits++
+			debitSum = debitSum.Add(trx.Amount.Abs())
+		} else {
+			credits++
+			creditSum = creditSum.Add(trx.Amount.Abs())
+		}
+	}
+
+	return credits, debits, creditSum, debitSum
+}
+
+func (st *Statement) location() *time.Location {
+	if st.Location == nil {
+		return transferhours.Istanbul
+	}
+
+	return st.Location
+}
+
+// lastDay returns the last day of the period, since the period excludes To.
+func (st *Statement) lastDay() time.Time {
+	return st.To.Add(-time.Nanosecond).In(st.location())
+}
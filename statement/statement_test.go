@@ -0,0 +1,169 @@
+package statement
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/birapi/go-corpbankclient"
+	"github.com/birapi/go-corpbankclient/transferhours"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func date(month time.Month, day, hour, min int) time.Time {
+	return time.Date(2024, month, day, hour, min, 0, 0, transferhours.Istanbul)
+}
+
+func trx(t time.Time, direction corpbankclient.TrxDirection, amount string) corpbankclient.Transaction {
+	return corpbankclient.Transaction{
+		Date:      t,
+		Amount:    decimal.RequireFromString(amount),
+		Direction: direction,
+	}
+}
+
+func TestBalanceAt(t *testing.T) {
+	transactions := []corpbankclient.Transaction{
+		trx(date(time.January, 5, 10, 0), corpbankclient.TrxDirectionIncoming, "100"),
+		trx(date(time.January, 10, 10, 0), corpbankclient.TrxDirectionOutgoing, "30"),
+		trx(date(time.January, 20, 10, 0), corpbankclient.TrxDirectionIncoming, "50"),
+	}
+
+	tests := []struct {
+		name      string
+		updatedAt time.Time
+		at        time.Time
+		want      string
+	}{
+		{"updated before the period, at the beginning", date(time.January, 1, 0, 0), date(time.January, 1, 0, 0), "1000"},
+		{"updated before the period, inside", date(time.January, 1, 0, 0), date(time.January, 7, 0, 0), "1100"},
+		{"updated before the period, at the end", date(time.January, 1, 0, 0), date(time.February, 1, 0, 0), "1120"},
+		{"updated inside the period, at the beginning", date(time.January, 10, 10, 0), date(time.January, 1, 0, 0), "930"},
+		{"updated inside the period, at a transaction", date(time.January, 10, 10, 0), date(time.January, 10, 10, 0), "1030"},
+		{"updated inside the period, at the end", date(time.January, 10, 10, 0), date(time.February, 1, 0, 0), "1050"},
+		{"updated after the period, at the beginning", date(time.February, 5, 0, 0), date(time.January, 1, 0, 0), "880"},
+		{"updated after the period, at the end", date(time.February, 5, 0, 0), date(time.February, 1, 0, 0), "1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance := &corpbankclient.AccountBalance{
+				Balance:       decimal.NewFromInt(1000),
+				LastUpdatedAt: tt.updatedAt,
+			}
+
+			if got := balanceAt(balance, transactions, tt.at); !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Fatalf("balanceAt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func testStatement(t *testing.T) *Statement {
+	account := Account{IBAN: "TR330006100519786457841326", Owner: "Birapi Yazılım A.Ş."}
+
+	t1 := trx(date(time.January, 5, 10, 0), corpbankclient.TrxDirectionIncoming, "1000")
+	t1.ID = uuid.MustParse("6f1c2a9e-8d4b-4c1e-9a57-1b2c3d4e5f60")
+	t1.RefCode = "INV/2024/01"
+	t1.Description = "Fatura ödemesi"
+	t1.TransferMethod = corpbankclient.TrxTransferMethodFAST
+	t1.Sender = &corpbankclient.TransactionParticipant{Name: "Ayşe Yılmaz", IBAN: "TR420001000000000000000001"}
+
+	t2 := trx(date(time.January, 10, 15, 30), corpbankclient.TrxDirectionOutgoing, "250.5")
+	t2.ID = uuid.MustParse("0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d")
+	t2.Description = "Kira"
+	t2.TransferMethod = corpbankclient.TrxTransferMethodEFT
+	t2.Recipient = &corpbankclient.TransactionParticipant{Name: "Acme A.Ş.", IBAN: "TR400006200000000000000001"}
+
+	// the reference has the separators of the MT940 fields
+	t3 := trx(date(time.January, 31, 23, 59), corpbankclient.TrxDirectionIncoming, "10")
+	t3.ID = uuid.MustParse("11111111-2222-4333-8444-555555555555")
+	t3.RefCode = "a//b:c-ğ"
+
+	// after the period, before the last update of the balance
+	t4 := trx(date(time.February, 2, 9, 0), corpbankclient.TrxDirectionIncoming, "100")
+	t4.ID = uuid.MustParse("99999999-8888-4777-8666-555555555555")
+
+	balance := &corpbankclient.AccountBalance{
+		Balance:       decimal.NewFromInt(5000),
+		LastUpdatedAt: date(time.February, 3, 0, 0),
+	}
+
+	st, err := New(account, date(time.January, 1, 0, 0), date(time.February, 1, 0, 0), balance,
+		[]corpbankclient.Transaction{t4, t3, t2, t1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st.CreatedAt = date(time.February, 1, 8, 0)
+
+	return st
+}
+
+func TestNew(t *testing.T) {
+	st := testStatement(t)
+
+	if st.ID != "240101-240131" {
+		t.Errorf("ID = %s, want 240101-240131", st.ID)
+	}
+
+	if len(st.Transactions) != 3 || st.Transactions[0].RefCode != "INV/2024/01" {
+		t.Errorf("the transactions of the period are not sorted: %v", st.Transactions)
+	}
+
+	if want := decimal.RequireFromString("4140.5"); !st.OpeningBalance.Equal(want) {
+		t.Errorf("OpeningBalance = %s, want %s", st.OpeningBalance, want)
+	}
+
+	if want := decimal.RequireFromString("4900"); !st.ClosingBalance.Equal(want) {
+		t.Errorf("ClosingBalance = %s, want %s", st.ClosingBalance, want)
+	}
+}
+
+// camtMsgID matches the message ID of the camt.053 messages, which is random.
+var camtMsgID = regexp.MustCompile(`<MsgId>[0-9a-f]{32}</MsgId>`)
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		file  string
+		write func(*bytes.Buffer, *Statement) error
+	}{
+		{"statement.camt053.xml", func(b *bytes.Buffer, st *Statement) error { return WriteCamt053(b, st) }},
+		{"statement.mt940", func(b *bytes.Buffer, st *Statement) error { return WriteMT940(b, st) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var b bytes.Buffer
+
+			if err := tt.write(&b, testStatement(t)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := camtMsgID.ReplaceAll(b.Bytes(), []byte("<MsgId>MSGID</MsgId>"))
+			path := filepath.Join("testdata", tt.file)
+
+			if *update {
+				if err := os.WriteFile(path, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Fatalf("the output does not match %s:\n%s", path, got)
+			}
+		})
+	}
+}
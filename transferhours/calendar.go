@@ -0,0 +1,223 @@
+// Package transferhours tells when the interbank transfers are processed in Turkey. The EFT system
+// is open on the business days within the working hours, while FAST and the intra-bank (HAVALE)
+// transfers are processed around the clock. The built-in holidays cover the years from 2024 to 2028,
+// the later ones can be added by AddYear. The days of the other years are reported with ErrUnknownYear,
+// instead of being treated as the business days.
+package transferhours
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultEFTOpen      = 9 * time.Hour
+	defaultEFTClose     = 17 * time.Hour
+	defaultHalfDayClose = 12 * time.Hour
+
+	firstYear = 2024
+	lastYear  = 2028
+
+	// maxSearchDays limits the search of the next open window.
+	maxSearchDays = 366
+)
+
+// ErrUnknownYear is returned for the days of the years which the holidays of the calendar do not cover.
+var ErrUnknownYear = errors.New("the holidays of the year are unknown")
+
+// Istanbul is the time zone of Turkey, which is UTC+3 all year round.
+var Istanbul = time.FixedZone("TRT", 3*60*60)
+
+// Calendar knows the EFT working hours and the public holidays.
+type Calendar struct {
+	// Location is the time zone of the working hours. Defaults to Istanbul.
+	Location *time.Location
+
+	// EFTOpen and EFTClose are the opening and closing times of the EFT system, since midnight.
+	// Default to 09:00 and 17:00.
+	EFTOpen  time.Duration
+	EFTClose time.Duration
+
+	// HalfDayClose is the closing time of the EFT system on the half days. Defaults to 12:00.
+	HalfDayClose time.Duration
+
+	holidays map[civilDate]Holiday
+	years    map[int]bool
+}
+
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// New returns a calendar with the default working hours and the built-in holidays.
+func New() *Calendar {
+	c := &Calendar{
+		Location:     Istanbul,
+		EFTOpen:      defaultEFTOpen,
+		EFTClose:     defaultEFTClose,
+		HalfDayClose: defaultHalfDayClose,
+	}
+
+	for _, h := range holidays(firstYear, lastYear) {
+		c.AddHoliday(h)
+	}
+
+	for y := firstYear; y <= lastYear; y++ {
+		c.markYear(y)
+	}
+
+	return c
+}
+
+// AddYear adds the holidays of a year which the built-in holidays do not cover, and marks the year
+// as known. The holidays on the same days every year are added automatically, so only the religious
+// holidays need to be given. It is not safe to call concurrently with the other methods.
+func (c *Calendar) AddYear(year int, holidays ...Holiday) {
+	for _, h := range fixedHolidays {
+		h.Year = year
+		c.AddHoliday(h)
+	}
+
+	for _, h := range holidays {
+		c.AddHoliday(h)
+	}
+
+	c.markYear(year)
+}
+
+func (c *Calendar) markYear(year int) {
+	if c.years == nil {
+		c.years = map[int]bool{}
+	}
+
+	c.years[year] = true
+}
+
+// AddHoliday adds a holiday to the calendar. A half day does not override a full day holiday.
+// It does not mark the year of the holiday as known, see AddYear. It is not safe to call concurrently with the other methods.
+func (c *Calendar) AddHoliday(h Holiday) {
+	if c.holidays == nil {
+		c.holidays = map[civilDate]Holiday{}
+	}
+
+	d := civilDate{h.Year, h.Month, h.Day}
+
+	if existing, ok := c.holidays[d]; ok && !existing.HalfDay && h.HalfDay {
+		return
+	}
+
+	c.holidays[d] = h
+}
+
+// Holiday returns the holiday on the day of t, in the location of the calendar.
+func (c *Calendar) Holiday(t time.Time) (Holiday, bool) {
+	y, m, d := t.In(c.location()).Date()
+	h, ok := c.holidays[civilDate{y, m, d}]
+
+	return h, ok
+}
+
+// IsBusinessDay reports whether the day of t is neither a weekend nor a full day holiday.
+// It returns ErrUnknownYear if the holidays of the year of t are unknown.
+func (c *Calendar) IsBusinessDay(t time.Time) (bool, error) {
+	if y := t.In(c.location()).Year(); !c.years[y] {
+		return false, errors.Wrapf(ErrUnknownYear, "%d", y)
+	}
+
+	switch t.In(c.location()).Weekday() {
+	case time.Saturday, time.Sunday:
+		return false, nil
+	}
+
+	h, ok := c.Holiday(t)
+
+	return !ok || h.HalfDay, nil
+}
+
+// EFTWindow returns the opening and closing times of the EFT system on the day of t.
+// It returns false if the EFT system is closed all day.
+func (c *Calendar) EFTWindow(t time.Time) (opensAt, closesAt time.Time, ok bool, err error) {
+	if businessDay, err := c.IsBusinessDay(t); !businessDay {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	y, m, d := t.In(c.location()).Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, c.location())
+
+	closeAt := c.eftClose()
+	if h, ok := c.Holiday(t); ok && h.HalfDay {
+		closeAt = c.halfDayClose()
+	}
+
+	return midnight.Add(c.eftOpen()), midnight.Add(closeAt), true, nil
+}
+
+// IsEFTOpen reports whether the EFT system is open at t.
+func (c *Calendar) IsEFTOpen(t time.Time) (bool, error) {
+	opensAt, closesAt, ok, err := c.EFTWindow(t)
+	return ok && !t.Before(opensAt) && t.Before(closesAt), err
+}
+
+// NextEFTOpen returns t if the EFT system is open at t, otherwise the next opening time.
+// It returns ErrUnknownYear if the search reaches a year of unknown holidays.
+func (c *Calendar) NextEFTOpen(t time.Time) (time.Time, error) {
+	open, err := c.IsEFTOpen(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if open {
+		return t, nil
+	}
+
+	for i := 0; i <= maxSearchDays; i++ {
+		y, m, d := t.In(c.location()).Date()
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, c.location())
+
+		opensAt, _, ok, err := c.EFTWindow(day)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if ok && opensAt.After(t) {
+			return opensAt, nil
+		}
+	}
+
+	return time.Time{}, errors.Errorf("the EFT system does not open within %d days after %s", maxSearchDays, t.Format(time.RFC3339))
+}
+
+func (c *Calendar) location() *time.Location {
+	if c.Location == nil {
+		return Istanbul
+	}
+
+	return c.Location
+}
+
+func (c *Calendar) eftOpen() time.Duration {
+	if c.EFTOpen <= 0 {
+		return defaultEFTOpen
+	}
+
+	return c.EFTOpen
+}
+
+func (c *Calendar) eftClose() time.Duration {
+	if c.EFTClose <= 0 {
+		return defaultEFTClose
+	}
+
+	return c.EFTClose
+}
+
+func (c *Calendar) halfDayClose() time.Duration {
+	if c.HalfDayClose <= 0 {
+		return defaultHalfDayClose
+	}
+
+	return c.HalfDayClose
+}
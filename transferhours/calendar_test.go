@@ -0,0 +1,150 @@
+package transferhours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func at(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, Istanbul)
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	tests := []struct {
+		name    string
+		day     time.Time
+		want    bool
+		wantErr error
+	}{
+		{"New Year's Day", at(2024, time.January, 1, 12, 0, 0), false, nil},
+		{"working day", at(2024, time.January, 2, 12, 0, 0), true, nil},
+		{"Saturday", at(2024, time.January, 6, 12, 0, 0), false, nil},
+		{"Sunday", at(2024, time.January, 7, 12, 0, 0), false, nil},
+		{"Ramadan Feast eve 2024", at(2024, time.April, 9, 12, 0, 0), true, nil},
+		{"Ramadan Feast 2024", at(2024, time.April, 10, 12, 0, 0), false, nil},
+		{"Ramadan Feast 2024, the last day", at(2024, time.April, 12, 12, 0, 0), false, nil},
+		{"Sacrifice Feast eve 2025", at(2025, time.June, 5, 12, 0, 0), true, nil},
+		{"Sacrifice Feast 2025, the last day", at(2025, time.June, 9, 12, 0, 0), false, nil},
+		{"after the Sacrifice Feast 2025", at(2025, time.June, 10, 12, 0, 0), true, nil},
+		{"Ramadan Feast eve 2026", at(2026, time.March, 19, 12, 0, 0), true, nil},
+		{"Ramadan Feast 2026", at(2026, time.March, 20, 12, 0, 0), false, nil},
+		{"Sacrifice Feast eve 2026", at(2026, time.May, 26, 12, 0, 0), true, nil},
+		{"Sacrifice Feast 2026", at(2026, time.May, 29, 12, 0, 0), false, nil},
+		{"Republic Day eve 2026", at(2026, time.October, 28, 12, 0, 0), true, nil},
+		{"Republic Day 2026", at(2026, time.October, 29, 12, 0, 0), false, nil},
+		{"Ramadan Feast eve 2027", at(2027, time.March, 8, 12, 0, 0), true, nil},
+		{"after the Ramadan Feast 2027", at(2027, time.March, 12, 12, 0, 0), true, nil},
+		{"Sacrifice Feast 2027", at(2027, time.May, 18, 12, 0, 0), false, nil},
+		{"Ramadan Feast eve 2028", at(2028, time.February, 25, 12, 0, 0), true, nil},
+		{"Ramadan Feast 2028, the last day", at(2028, time.February, 28, 12, 0, 0), false, nil},
+		{"New Year's Day in Istanbul, the day before in UTC", time.Date(2027, time.December, 31, 22, 0, 0, 0, time.UTC), false, nil},
+		{"the year before the built-in holidays", at(2023, time.June, 1, 12, 0, 0), false, ErrUnknownYear},
+		{"the year after the built-in holidays", at(2029, time.January, 2, 12, 0, 0), false, ErrUnknownYear},
+	}
+
+	c := New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.IsBusinessDay(tt.day)
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("IsBusinessDay(%s) error = %v, want %v", tt.day, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("IsBusinessDay(%s) = %v, want %v", tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsEFTOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"before the opening", at(2024, time.January, 2, 8, 59, 59), false},
+		{"at the opening", at(2024, time.January, 2, 9, 0, 0), true},
+		{"before the closing", at(2024, time.January, 2, 16, 59, 59), true},
+		{"at the closing", at(2024, time.January, 2, 17, 0, 0), false},
+		{"at the opening, in UTC", time.Date(2024, time.January, 2, 6, 0, 0, 0, time.UTC), true},
+		{"after midnight in Istanbul, before midnight in UTC", time.Date(2024, time.January, 1, 22, 0, 0, 0, time.UTC), false},
+		{"before the closing of a half day", at(2026, time.October, 28, 11, 59, 59), true},
+		{"at the closing of a half day", at(2026, time.October, 28, 12, 0, 0), false},
+		{"holiday", at(2024, time.April, 10, 10, 0, 0), false},
+		{"weekend", at(2024, time.January, 6, 10, 0, 0), false},
+	}
+
+	c := New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.IsEFTOpen(tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("IsEFTOpen(%s) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextEFTOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"open", at(2024, time.January, 2, 10, 0, 0), at(2024, time.January, 2, 10, 0, 0)},
+		{"before the opening", at(2024, time.January, 2, 7, 0, 0), at(2024, time.January, 2, 9, 0, 0)},
+		{"after the closing", at(2024, time.January, 2, 17, 0, 0), at(2024, time.January, 3, 9, 0, 0)},
+		{"Friday evening", at(2024, time.January, 5, 18, 0, 0), at(2024, time.January, 8, 9, 0, 0)},
+		{"half day afternoon before a feast", at(2024, time.April, 9, 13, 0, 0), at(2024, time.April, 15, 9, 0, 0)},
+	}
+
+	c := New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.NextEFTOpen(tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Fatalf("NextEFTOpen(%s) = %s, want %s", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownYear(t *testing.T) {
+	c := New()
+	lastFriday := at(2028, time.December, 29, 18, 0, 0)
+
+	if _, err := c.NextEFTOpen(lastFriday); !errors.Is(err, ErrUnknownYear) {
+		t.Fatalf("NextEFTOpen(%s) = %v, want ErrUnknownYear", lastFriday, err)
+	}
+
+	if _, err := c.IsEFTOpen(at(2029, time.January, 2, 10, 0, 0)); !errors.Is(err, ErrUnknownYear) {
+		t.Fatalf("IsEFTOpen() = %v, want ErrUnknownYear", err)
+	}
+
+	c.AddYear(2029)
+
+	got, err := c.NextEFTOpen(lastFriday)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// January 1 is added with the year
+	if want := at(2029, time.January, 2, 9, 0, 0); !got.Equal(want) {
+		t.Fatalf("NextEFTOpen(%s) = %s, want %s", lastFriday, got, want)
+	}
+}
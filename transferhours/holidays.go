@@ -0,0 +1,110 @@
+package transferhours
+
+import "time"
+
+// Holiday is a public holiday in Turkey. The banks close at noon on the half days,
+// which are the eves of the religious holidays and the Republic Day.
+type Holiday struct {
+	Year    int
+	Month   time.Month
+	Day     int
+	Name    string
+	HalfDay bool
+}
+
+const (
+	newYearsDay    = "Yılbaşı"
+	sovereigntyDay = "Ulusal Egemenlik ve Çocuk Bayramı"
+	labourDay      = "Emek ve Dayanışma Günü"
+	youthDay       = "Atatürk'ü Anma, Gençlik ve Spor Bayramı"
+	democracyDay   = "Demokrasi ve Milli Birlik Günü"
+	victoryDay     = "Zafer Bayramı"
+	republicDay    = "Cumhuriyet Bayramı"
+	ramadanFeast   = "Ramazan Bayramı"
+	sacrificeFeast = "Kurban Bayramı"
+)
+
+// fixedHolidays are observed on the same day every year.
+var fixedHolidays = []Holiday{
+	{Month: time.January, Day: 1, Name: newYearsDay},
+	{Month: time.April, Day: 23, Name: sovereigntyDay},
+	{Month: time.May, Day: 1, Name: labourDay},
+	{Month: time.May, Day: 19, Name: youthDay},
+	{Month: time.July, Day: 15, Name: democracyDay},
+	{Month: time.August, Day: 30, Name: victoryDay},
+	{Month: time.October, Day: 28, Name: republicDay, HalfDay: true},
+	{Month: time.October, Day: 29, Name: republicDay},
+}
+
+// religiousFeasts are the first days of the religious holidays, which follow the lunar calendar.
+// The Ramadan Feast lasts 3 days, and the Sacrifice Feast lasts 4 days, both following a half day eve.
+var religiousFeasts = []struct {
+	name  string
+	days  int
+	dates []time.Time
+}{
+	{
+		name: ramadanFeast,
+		days: 3,
+		dates: []time.Time{
+			date(2024, time.April, 10),
+			date(2025, time.March, 30),
+			date(2026, time.March, 20),
+			date(2027, time.March, 9),
+			date(2028, time.February, 26),
+		},
+	},
+	{
+		name: sacrificeFeast,
+		days: 4,
+		dates: []time.Time{
+			date(2024, time.June, 16),
+			date(2025, time.June, 6),
+			date(2026, time.May, 27),
+			date(2027, time.May, 16),
+			date(2028, time.May, 5),
+		},
+	},
+}
+
+// holidays returns the built-in holidays of the given years.
+func holidays(fromYear, toYear int) []Holiday {
+	var list []Holiday
+
+	for y := fromYear; y <= toYear; y++ {
+		for _, h := range fixedHolidays {
+			h.Year = y
+			list = append(list, h)
+		}
+	}
+
+	for _, f := range religiousFeasts {
+		for _, d := range f.dates {
+			if d.Year() < fromYear || d.Year() > toYear {
+				continue
+			}
+
+			list = append(list, holiday(d.AddDate(0, 0, -1), f.name, true))
+
+			for i := 0; i < f.days; i++ {
+				list = append(list, holiday(d.AddDate(0, 0, i), f.name, false))
+			}
+		}
+	}
+
+	return list
+}
+
+func holiday(d time.Time, name string, halfDay bool) Holiday {
+	return Holiday{
+		Year:    d.Year(),
+		Month:   d.Month(),
+		Day:     d.Day(),
+		Name:    name,
+		HalfDay: halfDay,
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,139 @@
+package corpbankclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var errUnknownSigner = errors.New("unknown signer")
+
+// WebhookVerifier verifies the webhook bearer tokens against a keyring of API keys.
+// The secret is looked up by the API key ID of the token, so the notifications signed
+// by any of the keys are accepted during an API key rotation. It is safe for concurrent
+// use, and the keyring can be reloaded without interrupting the webhook handlers.
+type WebhookVerifier struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]Signer
+}
+
+// NewWebhookVerifier returns a webhook verifier with the given API keys.
+func NewWebhookVerifier(apiCreds ...Credentials) (*WebhookVerifier, error) {
+	v := &WebhookVerifier{}
+
+	if err := v.SetKeys(apiCreds...); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return v, nil
+}
+
+// SetKeys replaces the whole keyring with the given API keys.
+func (v *WebhookVerifier) SetKeys(apiCreds ...Credentials) error {
+	keys := make(map[uuid.UUID]Signer, len(apiCreds))
+
+	for _, creds := range apiCreds {
+		keyID, keySec, err := parseCredentials(creds)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		keys[keyID] = NewHMACSigner(keySec)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.keys = keys
+
+	return nil
+}
+
+// AddKey adds the API key to the keyring, or replaces its secret if it already exists.
+func (v *WebhookVerifier) AddKey(apiCreds Credentials) error {
+	keyID, keySec, err := parseCredentials(apiCreds)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	v.AddSigner(keyID, NewHMACSigner(keySec))
+
+	return nil
+}
+
+// AddSigner adds the API key held by the signer to the keyring, or replaces its signer if it already exists.
+func (v *WebhookVerifier) AddSigner(apiKeyID uuid.UUID, signer Signer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.keys == nil {
+		v.keys = map[uuid.UUID]Signer{}
+	}
+
+	v.keys[apiKeyID] = signer
+}
+
+// RemoveKey removes the API key from the keyring.
+func (v *WebhookVerifier) RemoveKey(apiKeyID uuid.UUID) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.keys, apiKeyID)
+}
+
+// KeyIDs returns the IDs of the API keys in the keyring.
+func (v *WebhookVerifier) KeyIDs() []uuid.UUID {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	ids := make([]uuid.UUID, 0, len(v.keys))
+	for id := range v.keys {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// Verify verifies the token signed over the content by one of the API keys in the keyring.
+func (v *WebhookVerifier) Verify(token *BearerToken, contentToSign []byte, maxClockSkew time.Duration) error {
+	signer, err := v.signer(token.APIKeyID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := token.VerifyWith(signer, nil, contentToSign, maxClockSkew); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// VerifyRequest verifies the token signed over the HTTP request and its body by one of the API keys
+// in the keyring, by the signing scheme of the token.
+func (v *WebhookVerifier) VerifyRequest(token *BearerToken, req *http.Request, body []byte, maxClockSkew time.Duration) error {
+	signer, err := v.signer(token.APIKeyID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := token.VerifyWith(signer, req, body, maxClockSkew); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (v *WebhookVerifier) signer(apiKeyID uuid.UUID) (Signer, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	signer, ok := v.keys[apiKeyID]
+	if !ok {
+		return nil, errors.Wrapf(errUnknownSigner, "API key is not in the keyring: %s", apiKeyID)
+	}
+
+	return signer, nil
+}
@@ -1,88 +1,316 @@
 package corpbankclient
 
 import (
-	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 )
 
 type WebhookHandler func(context.Context, Transaction) error
 
+type PaymentStatusHandler func(context.Context, Payment) error
+
+type WebhookEventType string
+
+const (
+	WebhookEventTransaction   WebhookEventType = "TRANSACTION"
+	WebhookEventPaymentStatus WebhookEventType = "PAYMENT_STATUS"
+)
+
+// WebhookEvent is the envelope of the webhook notifications. The notifications without
+// an event type are considered as transaction notifications.
+type WebhookEvent struct {
+	Type WebhookEventType `json:"eventType"`
+	Data json.RawMessage  `json:"data"`
+}
+
+// WebhookHandlers holds the handlers of the webhook events. The events without a handler
+// are acknowledged without being processed.
+type WebhookHandlers struct {
+	Transaction   WebhookHandler
+	PaymentStatus PaymentStatusHandler
+}
+
+// WebhookHandler returns an HTTP handler which verifies the transaction notifications and passes them to the handler.
 func (c *Client) WebhookHandler(handler WebhookHandler) func(http.ResponseWriter, *http.Request) {
+	return c.WebhookEventHandler(WebhookHandlers{Transaction: handler})
+}
+
+// WebhookEventHandler returns an HTTP handler which verifies the webhook notifications
+// and dispatches them to the handlers by their event types.
+func (c *Client) WebhookEventHandler(handlers WebhookHandlers) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 
-		token := &BearerToken{}
-
-		hdrs := r.Header.Values("Authorization")
-		if l := len(hdrs); l == 0 {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Missing `Authorization` header."))
+		token, payload, ok := c.verifyWebhook(w, r)
+		if !ok {
 			return
+		}
 
-		} else if l > 1 {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Multiple `Authorization` header."))
-			return
+		// the signature is unique for each delivery, and it expires with the token timestamp
+		keys := []webhookKey{{"sig:" + hex.EncodeToString(token.Signature), 2 * c.maxTimeDiff}}
 
-		} else if hdr := strings.TrimSpace(hdrs[0]); len(hdr) < 7 {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Incomplete `Authorization` header."))
+		if statusCode, msg := c.claimWebhook(r, keys[0]); statusCode != 0 {
+			w.WriteHeader(statusCode)
+			w.Write([]byte(msg))
 			return
+		}
 
-		} else if v := strings.ToLower(hdr[:7]); v != "bearer " {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid `Authorization` token type."))
-			return
+		// fail writes the error response, and releases the claimed keys to let the notification to be redelivered
+		fail := func(statusCode int, msg string) {
+			if err := c.releaseWebhook(r, keys); err != nil {
+				msg += fmt.Sprintf(" (%s)", err.Error())
+			}
 
-		} else if v := strings.TrimSpace(hdr[7:]); len(v) == 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Missing bearer token."))
-			return
+			w.WriteHeader(statusCode)
+			w.Write([]byte(msg))
+		}
 
-		} else if err := token.Unpack(v); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(fmt.Sprintf("Invalid bearer token: %s", err.Error())))
+		event := &WebhookEvent{}
+		if err := json.Unmarshal(payload, event); err != nil {
+			fail(http.StatusBadRequest, fmt.Sprintf("Invalid request payload: %s", err.Error()))
 			return
 		}
 
-		payload, err := io.ReadAll(r.Body)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(fmt.Sprintf("Unable to read request body: %s", err.Error())))
-			return
+		if event.Type == "" {
+			event.Type = WebhookEventTransaction
+			event.Data = payload
 		}
 
-		if err := token.Verify(c.keySec, payload, c.maxTimeDiff); err != nil {
-			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte(fmt.Sprintf("Unable to verify the request signature: %s", err.Error())))
+		var (
+			handle   func(ctx context.Context) error
+			eventKey string
+		)
+
+		switch event.Type {
+		case WebhookEventTransaction:
+			if handlers.Transaction == nil {
+				break
+			}
+
+			trx := &Transaction{}
+			if err := json.Unmarshal(event.Data, trx); err != nil {
+				fail(http.StatusBadRequest, fmt.Sprintf("Invalid request payload: %s", err.Error()))
+				return
+			}
+
+			handle = func(ctx context.Context) error {
+				return handlers.Transaction(ctx, *trx)
+			}
+
+			if trx.ID != uuid.Nil {
+				eventKey = "trx:" + trx.ID.String()
+			}
+
+		case WebhookEventPaymentStatus:
+			if handlers.PaymentStatus == nil {
+				break
+			}
+
+			payment := &Payment{}
+			if err := json.Unmarshal(event.Data, payment); err != nil {
+				fail(http.StatusBadRequest, fmt.Sprintf("Invalid request payload: %s", err.Error()))
+				return
+			}
+
+			handle = func(ctx context.Context) error {
+				return handlers.PaymentStatus(ctx, *payment)
+			}
+
+			if payment.ID != uuid.Nil {
+				eventKey = "payment:" + payment.ID.String() + ":" + string(payment.Status)
+			}
+
+		default:
+			fail(http.StatusBadRequest, fmt.Sprintf("Unsupported event type: `%s`", event.Type))
 			return
 		}
 
-		if !bytes.Equal(token.APIKeyID[:], c.keyID[:]) {
-			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte(fmt.Sprintf("Illegal signer: %s", err.Error())))
-			return
+		msg := fmt.Sprintf("The webhook notification has been ignored, there is no handler for the event type: `%s`", event.Type)
+
+		if handle != nil {
+			if eventKey != "" {
+				key := webhookKey{eventKey, c.replayTTL}
+
+				if statusCode, msg := c.claimWebhook(r, key); statusCode != 0 {
+					fail(statusCode, msg)
+					return
+				}
+
+				keys = append(keys, key)
+			}
+
+			if err := handle(r.Context()); err != nil {
+				fail(http.StatusInternalServerError, fmt.Sprintf("An error occurred while processing the webhook notification: %s", err.Error()))
+				return
+			}
+
+			msg = "The webhook notification has been processed successfully."
 		}
 
-		trx := &Transaction{}
-		if err := json.Unmarshal(payload, trx); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(fmt.Sprintf("Invalid request payload: %s", err.Error())))
+		// a failure to record the notification is reported to let it to be redelivered,
+		// since the redelivery would be acknowledged as a replay otherwise
+		if err := c.recordWebhook(r, keys); err != nil {
+			fail(http.StatusInternalServerError, fmt.Sprintf("Unable to record the webhook notification for replay: %s", err.Error()))
 			return
 		}
 
-		if err := handler(r.Context(), *trx); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(fmt.Sprintf("An error occurred while processing the webhook notification: %s", err.Error())))
-			return
+		// the locks which can not be removed expire by their TTL
+		if err := c.releaseWebhook(r, keys); err != nil {
+			msg += fmt.Sprintf(" (%s)", err.Error())
 		}
 
 		w.WriteHeader(http.StatusAccepted)
-		w.Write([]byte("The webhook notification has been processed successfully."))
+		w.Write([]byte(msg))
+	}
+}
+
+// webhookKey is a replay key of the webhook notifications.
+type webhookKey struct {
+	key string
+	ttl time.Duration
+}
+
+func (k webhookKey) lock() string {
+	return "lock:" + k.key
+}
+
+// claimWebhook locks the key while the notification is in progress, so that the concurrent deliveries
+// are rejected to be redelivered later, instead of being acknowledged before the notification is
+// processed. The key itself is recorded only after the notification is processed, so it is checked
+// by adding and removing it while it is locked. It returns the status code and the message of the
+// response if the key is locked, already recorded, or it can not be checked, and zero otherwise.
+func (c *Client) claimWebhook(r *http.Request, k webhookKey) (int, string) {
+	if c.replayStore == nil {
+		return 0, ""
 	}
+
+	locked, err := c.replayStore.Add(r.Context(), k.lock(), webhookLockTTL)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to check the webhook notification for replay: %s", err.Error())
+	}
+
+	if !locked {
+		return http.StatusConflict, "The webhook notification is being processed by another delivery."
+	}
+
+	added, err := c.replayStore.Add(r.Context(), k.key, k.ttl)
+	if err == nil && added {
+		err = c.replayStore.Remove(r.Context(), k.key)
+	}
+
+	statusCode, msg := 0, ""
+
+	switch {
+	case err != nil:
+		statusCode, msg = http.StatusInternalServerError, fmt.Sprintf("Unable to check the webhook notification for replay: %s", err.Error())
+	case !added:
+		statusCode, msg = http.StatusOK, "The webhook notification has already been processed."
+	default:
+		return 0, ""
+	}
+
+	if err := c.releaseWebhook(r, []webhookKey{k}); err != nil {
+		msg += fmt.Sprintf(" (%s)", err.Error())
+	}
+
+	return statusCode, msg
+}
+
+// recordWebhook records the keys of the processed notification.
+func (c *Client) recordWebhook(r *http.Request, keys []webhookKey) error {
+	if c.replayStore == nil {
+		return nil
+	}
+
+	for _, k := range keys {
+		if _, err := c.replayStore.Add(r.Context(), k.key, k.ttl); err != nil {
+			return errors.Wrapf(err, "unable to add the replay key: `%s`", k.key)
+		}
+	}
+
+	return nil
+}
+
+// releaseWebhook removes the locks of the keys, and returns the first error.
+func (c *Client) releaseWebhook(r *http.Request, keys []webhookKey) error {
+	if c.replayStore == nil {
+		return nil
+	}
+
+	var firstErr error
+
+	for _, k := range keys {
+		if err := c.replayStore.Remove(r.Context(), k.lock()); err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "unable to remove the replay key: `%s`", k.lock())
+		}
+	}
+
+	return firstErr
+}
+
+// verifyWebhook verifies the bearer token of the webhook request and returns the request payload.
+// It writes the error response and returns false if the request can not be verified.
+func (c *Client) verifyWebhook(w http.ResponseWriter, r *http.Request) (*BearerToken, []byte, bool) {
+	token := &BearerToken{}
+
+	hdrs := r.Header.Values("Authorization")
+	if l := len(hdrs); l == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Missing `Authorization` header."))
+		return nil, nil, false
+
+	} else if l > 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Multiple `Authorization` header."))
+		return nil, nil, false
+
+	} else if hdr := strings.TrimSpace(hdrs[0]); len(hdr) < 7 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Incomplete `Authorization` header."))
+		return nil, nil, false
+
+	} else if v := strings.ToLower(hdr[:7]); v != "bearer " {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid `Authorization` token type."))
+		return nil, nil, false
+
+	} else if v := strings.TrimSpace(hdr[7:]); len(v) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Missing bearer token."))
+		return nil, nil, false
+
+	} else if err := token.Unpack(v); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("Invalid bearer token: %s", err.Error())))
+		return nil, nil, false
+	}
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Unable to read request body: %s", err.Error())))
+		return nil, nil, false
+	}
+
+	if err := c.verifier.VerifyRequest(token, r, payload, c.maxTimeDiff); errors.Is(err, errUnknownSigner) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(fmt.Sprintf("Illegal signer: %s", token.APIKeyID)))
+		return nil, nil, false
+
+	} else if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(fmt.Sprintf("Unable to verify the request signature: %s", err.Error())))
+		return nil, nil, false
+	}
+
+	return token, payload, true
 }